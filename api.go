@@ -1,55 +1,113 @@
 package microdotblog
 
-import "time"
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"time"
+)
 
 // Feed represents an entire feed with posts and metadata.
+// It follows JSON Feed 1.1, see https://jsonfeed.org/version/1.1.
 type Feed struct {
-	Version             string `json:"version"`
-	Title               string `json:"title"`
-	HomepageURL         string `json:"home_page_url"`
-	FeedURL             string `json:"feed_url"`
-	Items               []Post `json:"items"`
-	Author              Author `json:"author"`
-	MicroblogProperties struct {
-		About          string `json:"about"`
-		ID             int64  `json:"id,string"`
-		Username       string `json:"username"`
-		Bio            string `json:"bio"`
-		IsFollowing    bool   `json:"is_following"`
-		IsYou          bool   `json:"is_you"`
-		FollowingCount int    `json:"following_count"`
-	} `json:"_microblog"`
+	Version     string `json:"version"`
+	Title       string `json:"title"`
+	HomepageURL string `json:"home_page_url"`
+	FeedURL     string `json:"feed_url"`
+	Description string `json:"description,omitempty"`
+	UserComment string `json:"user_comment,omitempty"`
+	// NextURL is the URL of the next page of the feed, if there is one.
+	NextURL  string `json:"next_url,omitempty"`
+	Icon     string `json:"icon,omitempty"`
+	Favicon  string `json:"favicon,omitempty"`
+	Language string `json:"language,omitempty"`
+	Expired  bool   `json:"expired,omitempty"`
+	Hubs     []Hub  `json:"hubs,omitempty"`
+	Items    []Post `json:"items"`
+	// Author is from JSON Feed 1.0. Feeds in version 1.1 use Authors.
+	Author              Author        `json:"author"`
+	Authors             []Author      `json:"authors,omitempty"`
+	MicroblogProperties FeedMicroblog `json:"_microblog"`
 }
 
 // Post represents a single post.
 type Post struct {
-	ID                  int64     `json:"id,string"`
-	URL                 string    `json:"url"`
-	ContentHTML         string    `json:"content_html"`
-	DatePublished       time.Time `json:"date_published"`
-	Author              Author    `json:"author"`
-	MicroblogProperties struct {
-		IsDeletable  bool   `json:"is_deletable"`
-		IsFavorite   bool   `json:"is_favourite"`
-		DateRelative string `json:"date_relative"`
-	} `json:"_microblog"`
+	ID            int64     `json:"id,string"`
+	URL           string    `json:"url"`
+	ExternalURL   string    `json:"external_url,omitempty"`
+	Title         string    `json:"title,omitempty"`
+	ContentHTML   string    `json:"content_html"`
+	ContentText   string    `json:"content_text,omitempty"`
+	Summary       string    `json:"summary,omitempty"`
+	Image         string    `json:"image,omitempty"`
+	BannerImage   string    `json:"banner_image,omitempty"`
+	DatePublished time.Time `json:"date_published"`
+	// DateModified is nil if the post has not been modified.
+	DateModified *time.Time `json:"date_modified,omitempty"`
+	// Author is from JSON Feed 1.0. Feeds in version 1.1 use Authors.
+	Author              Author        `json:"author"`
+	Authors             []Author      `json:"authors,omitempty"`
+	Tags                TagList       `json:"tags,omitempty"`
+	Language            string        `json:"language,omitempty"`
+	Attachments         []Attachment  `json:"attachments,omitempty"`
+	MicroblogProperties PostMicroblog `json:"_microblog"`
 }
 
-// Photo represents a photo.
-// TODO: implement as a io.Reader (or ReadCloser)
+// Photo represents a photo that can be uploaded to the media endpoint.
+// Use NewPhoto, NewPhotoFromFile or NewPhotoFromBytes to create one.
 type Photo struct {
-	path string
+	data        []byte
+	reader      *photoReader
+	filename    string
+	contentType string
+}
+
+// photoReader is shared by the copies of a Photo so that they all
+// know when the reader has been used up.
+type photoReader struct {
+	r    io.Reader
+	read bool
+}
+
+// NewPhoto creates a Photo that reads its data from r.
+// The filename is sent along with the upload and
+// contentType should be the MIME type of the image, e.g. "image/jpeg".
+// Since r can only be read once, the photo can only be uploaded once.
+// Use NewPhotoFromBytes for a photo that can be uploaded again.
+func NewPhoto(r io.Reader, filename, contentType string) Photo {
+	return Photo{reader: &photoReader{r: r}, filename: filename, contentType: contentType}
+}
+
+// NewPhotoFromBytes creates a Photo from a byte slice.
+// If contentType is empty it is detected from the data.
+func NewPhotoFromBytes(data []byte, filename, contentType string) Photo {
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	return Photo{data: data, filename: filename, contentType: contentType}
+}
+
+// NewPhotoFromFile creates a Photo from the file at the given path.
+// The MIME type is guessed from the file extension and falls back
+// to detecting it from the file's contents.
+func NewPhotoFromFile(path string) (Photo, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Photo{}, err
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	return NewPhotoFromBytes(data, filepath.Base(path), contentType), nil
 }
 
 // Author is a represetation of the author of a post.
 type Author struct {
-	Name                string `json:"name"`
-	URL                 string `json:"url"`
-	Avatar              string `json:"avatar"`
-	MicroblogProperties struct {
-		Username    string `json:"username"`
-		IsFollowing bool   `json:"is_following"`
-	} `json:"_microblog"`
+	Name                string          `json:"name"`
+	URL                 string          `json:"url"`
+	Avatar              string          `json:"avatar"`
+	MicroblogProperties AuthorMicroblog `json:"_microblog"`
 }
 
 // Check is returned when checking for new posts.
@@ -71,53 +129,216 @@ type User struct {
 // APIClient gives access to the API.
 type APIClient interface {
 	// GetPosts gets all posts from a feed.
-	GetPosts() (*Feed, error)
+	// Pass FeedOptions to page through older or newer posts.
+	GetPosts(opts ...FeedOptions) (*Feed, error)
+
+	// GetPostsContext is like GetPosts but uses ctx for the request.
+	GetPostsContext(ctx context.Context, opts ...FeedOptions) (*Feed, error)
 
 	// GetMentions gets a feed with mentions of the current user.
-	GetMentions() (*Feed, error)
+	GetMentions(opts ...FeedOptions) (*Feed, error)
+
+	// GetMentionsContext is like GetMentions but uses ctx for the request.
+	GetMentionsContext(ctx context.Context, opts ...FeedOptions) (*Feed, error)
 
 	// GetFavourites gets a feed of the current user's favourites.
-	GetFavourites() (*Feed, error)
+	GetFavourites(opts ...FeedOptions) (*Feed, error)
+
+	// GetFavouritesContext is like GetFavourites but uses ctx for the request.
+	GetFavouritesContext(ctx context.Context, opts ...FeedOptions) (*Feed, error)
 
 	// Discover returns a feed of curated posts.
-	Discover() (*Feed, error)
+	Discover(opts ...FeedOptions) (*Feed, error)
+
+	// DiscoverContext is like Discover but uses ctx for the request.
+	DiscoverContext(ctx context.Context, opts ...FeedOptions) (*Feed, error)
 
 	// GetUserPosts gets the timeline of the specified user.
-	GetUserPosts(username string) (*Feed, error)
+	GetUserPosts(username string, opts ...FeedOptions) (*Feed, error)
+
+	// GetUserPostsContext is like GetUserPosts but uses ctx for the request.
+	GetUserPostsContext(ctx context.Context, username string, opts ...FeedOptions) (*Feed, error)
+
+	// IteratePosts walks the current user's timeline page by page,
+	// starting at the page described by opts.
+	IteratePosts(opts FeedOptions) *FeedIterator
+
+	// IteratePostsContext is like IteratePosts but uses ctx for all its requests.
+	IteratePostsContext(ctx context.Context, opts FeedOptions) *FeedIterator
+
+	// IterateMentions walks the mentions of the current user page by page.
+	IterateMentions(opts FeedOptions) *FeedIterator
+
+	// IterateMentionsContext is like IterateMentions but uses ctx for all its requests.
+	IterateMentionsContext(ctx context.Context, opts FeedOptions) *FeedIterator
+
+	// IterateFavourites walks the current user's favourites page by page.
+	IterateFavourites(opts FeedOptions) *FeedIterator
+
+	// IterateFavouritesContext is like IterateFavourites but uses ctx for all its requests.
+	IterateFavouritesContext(ctx context.Context, opts FeedOptions) *FeedIterator
+
+	// IterateUserPosts walks the timeline of the specified user page by page.
+	IterateUserPosts(username string, opts FeedOptions) *FeedIterator
+
+	// IterateUserPostsContext is like IterateUserPosts but uses ctx for all its requests.
+	IterateUserPostsContext(ctx context.Context, username string, opts FeedOptions) *FeedIterator
 
 	// GetConversation gets all replies to a post.
 	GetConversation(ID int64) (*Feed, error)
 
+	// GetConversationContext is like GetConversation but uses ctx for the request.
+	GetConversationContext(ctx context.Context, ID int64) (*Feed, error)
+
 	// Check looks for posts newer than the post with the specified ID.
 	Check(sinceID int64) (*Check, error)
 
+	// CheckContext is like Check but uses ctx for the request.
+	CheckContext(ctx context.Context, sinceID int64) (*Check, error)
+
 	// Favourite marks the post with the given ID a favourite.
 	Favourite(ID int64) error
 
+	// FavouriteContext is like Favourite but uses ctx for the request.
+	FavouriteContext(ctx context.Context, ID int64) error
+
 	// Unfavourite removes favourite flag from the post with the specified ID.
 	Unfavourite(ID int64) error
 
+	// UnfavouriteContext is like Unfavourite but uses ctx for the request.
+	UnfavouriteContext(ctx context.Context, ID int64) error
+
 	// Reply sends a new Post with the specified message as a reply to the Post
-	// with the given ID.
+	// with the given ID. The returned Post has the URL of the reply.
 	Reply(ID int64, message string) (*Post, error)
 
+	// ReplyContext is like Reply but uses ctx for the request.
+	ReplyContext(ctx context.Context, ID int64, message string) (*Post, error)
+
 	// DeletePost removes the Post with the given ID.
 	DeletePost(ID int64) error
 
+	// DeletePostContext is like DeletePost but uses ctx for the request.
+	DeletePostContext(ctx context.Context, ID int64) error
+
 	// Follow will let the current user start following the user with the
 	// given username.
 	Follow(username string) error
 
+	// FollowContext is like Follow but uses ctx for the request.
+	FollowContext(ctx context.Context, username string) error
+
 	// Unfollow will remove the user with the specified username from the list
 	// of users the current user follows.
 	Unfollow(username string) error
 
+	// UnfollowContext is like Unfollow but uses ctx for the request.
+	UnfollowContext(ctx context.Context, username string) error
+
 	// Followers lists the users the given user follows.
 	Followers(username string) ([]User, error)
 
+	// FollowersContext is like Followers but uses ctx for the request.
+	FollowersContext(ctx context.Context, username string) ([]User, error)
+
 	// Post posts a new update to the blog.
+	// The returned Post has the URL of the new post.
 	Post(message string) (*Post, error)
 
+	// PostContext is like Post but uses ctx for the request.
+	PostContext(ctx context.Context, message string) (*Post, error)
+
+	// CreateEntry creates a new post through Micropub with all the
+	// properties set on entry.
+	CreateEntry(entry MicropubEntry) (*Post, error)
+
+	// CreateEntryContext is like CreateEntry but uses ctx for the request.
+	CreateEntryContext(ctx context.Context, entry MicropubEntry) (*Post, error)
+
+	// UpdateEntry changes the properties of the post at postURL through
+	// Micropub.
+	UpdateEntry(postURL string, ops ...UpdateOperation) error
+
+	// UpdateEntryContext is like UpdateEntry but uses ctx for the request.
+	UpdateEntryContext(ctx context.Context, postURL string, ops ...UpdateOperation) error
+
+	// DeleteEntry deletes the post at postURL through Micropub.
+	DeleteEntry(postURL string) error
+
+	// DeleteEntryContext is like DeleteEntry but uses ctx for the request.
+	DeleteEntryContext(ctx context.Context, postURL string) error
+
+	// UndeleteEntry restores the deleted post at postURL through Micropub.
+	UndeleteEntry(postURL string) error
+
+	// UndeleteEntryContext is like UndeleteEntry but uses ctx for the request.
+	UndeleteEntryContext(ctx context.Context, postURL string) error
+
+	// MicropubConfig asks the Micropub server for its media endpoint,
+	// destinations, post types and syndication targets.
+	MicropubConfig() (*MicropubConfig, error)
+
+	// MicropubConfigContext is like MicropubConfig but uses ctx for the request.
+	MicropubConfigContext(ctx context.Context) (*MicropubConfig, error)
+
+	// Source gets the Micropub source of the post at postURL.
+	// If properties are given only those are returned.
+	Source(postURL string, properties ...string) (*MicropubSource, error)
+
+	// SourceContext is like Source but uses ctx for the request.
+	SourceContext(ctx context.Context, postURL string, properties ...string) (*MicropubSource, error)
+
+	// SyndicateTargets lists the services posts can be cross-posted to.
+	SyndicateTargets() ([]SyndicationTarget, error)
+
+	// SyndicateTargetsContext is like SyndicateTargets but uses ctx for the request.
+	SyndicateTargetsContext(ctx context.Context) ([]SyndicationTarget, error)
+
+	// Categories lists the categories used on the blog.
+	Categories() ([]string, error)
+
+	// CategoriesContext is like Categories but uses ctx for the request.
+	CategoriesContext(ctx context.Context) ([]string, error)
+
+	// GetBookmarks gets a feed of the current user's bookmarks.
+	GetBookmarks(opts ...FeedOptions) (*Feed, error)
+
+	// GetBookmarksContext is like GetBookmarks but uses ctx for the request.
+	GetBookmarksContext(ctx context.Context, opts ...FeedOptions) (*Feed, error)
+
+	// GetBookmarksByTag gets the bookmarks with the given tag.
+	GetBookmarksByTag(tag string, opts ...FeedOptions) (*Feed, error)
+
+	// GetBookmarksByTagContext is like GetBookmarksByTag but uses ctx for the request.
+	GetBookmarksByTagContext(ctx context.Context, tag string, opts ...FeedOptions) (*Feed, error)
+
+	// IterateBookmarks walks the current user's bookmarks page by page.
+	IterateBookmarks(opts FeedOptions) *FeedIterator
+
+	// IterateBookmarksContext is like IterateBookmarks but uses ctx for all its requests.
+	IterateBookmarksContext(ctx context.Context, opts FeedOptions) *FeedIterator
+
+	// BookmarkTags lists the tags used on the current user's bookmarks.
+	BookmarkTags() ([]string, error)
+
+	// BookmarkTagsContext is like BookmarkTags but uses ctx for the request.
+	BookmarkTagsContext(ctx context.Context) ([]string, error)
+
+	// Bookmark bookmarks the web page at bookmarkURL.
+	Bookmark(bookmarkURL string) (*Post, error)
+
+	// BookmarkContext is like Bookmark but uses ctx for the request.
+	BookmarkContext(ctx context.Context, bookmarkURL string) (*Post, error)
+
+	// DeleteBookmark removes the bookmark with the given ID.
+	DeleteBookmark(ID int64) error
+
+	// DeleteBookmarkContext is like DeleteBookmark but uses ctx for the request.
+	DeleteBookmarkContext(ctx context.Context, ID int64) error
+
 	// PostPhoto posts a new update including a photo.
 	PostPhoto(message string, photo Photo) (*Post, error)
+
+	// PostPhotoContext is like PostPhoto but uses ctx for the request.
+	PostPhotoContext(ctx context.Context, message string, photo Photo) (*Post, error)
 }
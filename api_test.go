@@ -2,9 +2,14 @@ package microdotblog
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -123,6 +128,27 @@ func makeMockClient(token, responseData string) APIClient {
 	return c
 }
 
+type handlerClient struct {
+	handler http.HandlerFunc
+}
+
+func (h handlerClient) Do(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	h.handler(rec, req)
+	return rec.Result(), nil
+}
+
+func makeHandlerClient(token string, handler http.HandlerFunc) APIClient {
+	c := apiClient{
+		httpClient: aClient{
+			httpClient: handlerClient{handler: handler},
+			token:      token,
+		},
+	}
+
+	return c
+}
+
 func makeFailingMockClient(statusCode int, status string) APIClient {
 	body := body{bytes.NewBufferString(status)}
 	response := http.Response{Body: body, StatusCode: statusCode, Status: status}
@@ -332,6 +358,70 @@ func TestGetConversation(t *testing.T) {
 	}
 }
 
+func TestGetPostsContext(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "request-scoped")
+
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		if v, _ := r.Context().Value(key{}).(string); v != "request-scoped" {
+			t.Errorf("Expected the request to carry the caller's context")
+		}
+		w.Write([]byte(posts))
+	})
+
+	if _, err := c.GetPostsContext(ctx); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/posts/all" {
+			t.Errorf("Expected request to /posts/all, got %s", r.URL.Path)
+		}
+		if ua := r.Header.Get("User-Agent"); ua != "test-agent/1.0" {
+			t.Errorf("Expected user agent 'test-agent/1.0', got '%s'", ua)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "ABCD12345" {
+			t.Errorf("Expected token in Authorization header, got '%s'", auth)
+		}
+		w.Write([]byte(posts))
+	}))
+	defer server.Close()
+
+	c := NewAPIClient("ABCD12345",
+		WithHTTPClient(server.Client()),
+		WithBaseURL(server.URL+"/"),
+		WithUserAgent("test-agent/1.0"),
+		WithTimeout(time.Second),
+	)
+
+	feed, err := c.GetPosts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Items) != 3 {
+		t.Errorf("Returned feed doesn't look right")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	c := NewAPIClient("ABCD12345", WithBaseURL(server.URL), WithTimeout(10*time.Millisecond))
+	if _, err := c.GetPosts(); err == nil {
+		t.Error("Expected the request to time out")
+	}
+}
+
 func TestCheck(t *testing.T) {
 	responseBody := `{"count":5,"check_seconds":120}`
 	c := makeMockClient("ABCD12345", responseBody)
@@ -374,6 +464,94 @@ func TestPost(t *testing.T) {
 	}
 }
 
+func TestPostPhoto(t *testing.T) {
+	var uploaded, created bool
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Query().Get("q") == "config":
+			w.Write([]byte(`{"media-endpoint":"https://micro.blog/micropub/media"}`))
+		case r.Method == "POST" && r.URL.Path == "/micropub/media":
+			file, header, err := r.FormFile("file")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			if header.Filename != "cat.png" {
+				t.Errorf("Expected filename 'cat.png', got '%s'", header.Filename)
+			}
+			if contentType := header.Header.Get("Content-Type"); contentType != "image/png" {
+				t.Errorf("Expected content type 'image/png', got '%s'", contentType)
+			}
+			uploaded = true
+			w.Header().Set("Location", "https://example.org/uploads/cat.png")
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == "POST" && r.URL.Path == "/micropub":
+			if photo := r.FormValue("photo[]"); photo != "https://example.org/uploads/cat.png" {
+				t.Errorf("Expected photo URL from upload, got '%s'", photo)
+			}
+			if content := r.FormValue("content"); content != "Look at my cat" {
+				t.Errorf("Expected content 'Look at my cat', got '%s'", content)
+			}
+			created = true
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	photo := NewPhotoFromBytes([]byte("not really a png"), "cat.png", "image/png")
+	if _, err := c.PostPhoto("Look at my cat", photo); err != nil {
+		t.Error(err)
+	}
+	if !uploaded || !created {
+		t.Errorf("Expected photo to be uploaded (%v) and post to be created (%v)", uploaded, created)
+	}
+}
+
+func TestUploadPhotoTwice(t *testing.T) {
+	var uploads []string
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET":
+			w.Write([]byte(`{"media-endpoint":"https://micro.blog/micropub/media"}`))
+		case r.URL.Path == "/micropub/media":
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			data, _ := ioutil.ReadAll(file)
+			uploads = append(uploads, string(data))
+			w.Header().Set("Location", "https://example.org/uploads/cat.png")
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusAccepted)
+		}
+	})
+
+	photo := NewPhotoFromBytes([]byte("not really a png"), "cat.png", "image/png")
+	for i := 0; i < 2; i++ {
+		if _, err := c.PostPhoto("Look at my cat", photo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(uploads) != 2 || uploads[0] != uploads[1] {
+		t.Errorf("Expected the same photo to be uploaded twice, got %q", uploads)
+	}
+
+	photo = NewPhoto(strings.NewReader("not really a png"), "cat.png", "image/png")
+	if _, err := c.PostPhoto("Look at my cat", photo); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.PostPhoto("Look at my cat", photo); err == nil {
+		t.Error("Expected an error when uploading a read photo again")
+	}
+	if len(uploads) != 3 {
+		t.Errorf("Expected 3 uploads, got %d", len(uploads))
+	}
+}
+
 func TestDeletePost(t *testing.T) {
 	c := makeMockClient("ABCD12345", "")
 	err := c.DeletePost(1234)
@@ -382,6 +560,61 @@ func TestDeletePost(t *testing.T) {
 	}
 }
 
+type trackingBody struct {
+	*bytes.Buffer
+	closed bool
+}
+
+func (b *trackingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+type trackingClient struct {
+	requests []*http.Request
+	bodies   []*trackingBody
+}
+
+func (c *trackingClient) Do(req *http.Request) (*http.Response, error) {
+	body := &trackingBody{Buffer: bytes.NewBufferString("{}")}
+	c.requests = append(c.requests, req)
+	c.bodies = append(c.bodies, body)
+	return &http.Response{StatusCode: 200, Header: http.Header{}, Body: body}, nil
+}
+
+func TestRequestsShareHeadersAndCloseBodies(t *testing.T) {
+	tc := &trackingClient{}
+	c := apiClient{
+		httpClient: aClient{httpClient: tc, token: "ABCD12345", userAgent: "test-agent"},
+	}
+
+	c.GetPosts()
+	c.Favourite(1234)
+	c.Unfavourite(1234)
+	c.DeletePost(1234)
+	c.Post("Hello")
+
+	methods := []string{"GET", "POST", "DELETE", "DELETE", "POST"}
+	if len(tc.requests) != len(methods) {
+		t.Fatalf("Expected %d requests, got %d", len(methods), len(tc.requests))
+	}
+
+	for i, req := range tc.requests {
+		if req.Method != methods[i] {
+			t.Errorf("Expected request %d to be %s, got %s", i, methods[i], req.Method)
+		}
+		if auth := req.Header.Get("Authorization"); auth != "ABCD12345" {
+			t.Errorf("Expected %s %s to send the token, got '%s'", req.Method, req.URL, auth)
+		}
+		if ua := req.Header.Get("User-Agent"); ua != "test-agent" {
+			t.Errorf("Expected %s %s to send the user agent, got '%s'", req.Method, req.URL, ua)
+		}
+		if !tc.bodies[i].closed {
+			t.Errorf("Expected the response body of %s %s to be closed", req.Method, req.URL)
+		}
+	}
+}
+
 func TestFollow(t *testing.T) {
 	c := makeMockClient("ABCD12345", "")
 	if err := c.Follow("manton"); err != nil {
@@ -389,6 +622,29 @@ func TestFollow(t *testing.T) {
 	}
 }
 
+func TestUsernamesAreEscaped(t *testing.T) {
+	var requests []string
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.EscapedPath()+" "+strings.Join(r.URL.Query()["username"], ","))
+		w.Write([]byte(`{"items":[]}`))
+	})
+
+	c.Follow("bob&username=alice")
+	c.Unfollow("john doe")
+	c.GetUserPosts("../john doe")
+	c.Followers("a/b?c")
+
+	expected := []string{
+		"/users/follow bob&username=alice",
+		"/users/unfollow john doe",
+		"/posts/..%2Fjohn%20doe ",
+		"/users/following/a%2Fb%3Fc ",
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Expected requests %q, got %q", expected, requests)
+	}
+}
+
 func TestNotFound(t *testing.T) {
 	c := makeFailingMockClient(404, "Not found")
 	_, err := c.GetPosts()
@@ -429,3 +685,82 @@ func TestClientError(t *testing.T) {
 		t.Errorf("Expected client error, got %v", err)
 	}
 }
+
+func TestErrorsIs(t *testing.T) {
+	testCases := []struct {
+		statusCode int
+		sentinel   error
+	}{
+		{401, ErrUnauthorized},
+		{403, ErrForbidden},
+		{404, ErrNotFound},
+		{404, ErrClientError},
+		{429, ErrRateLimited},
+		{429, ErrClientError},
+		{503, ErrServerError},
+	}
+
+	for _, tc := range testCases {
+		c := makeFailingMockClient(tc.statusCode, "failed")
+		_, err := c.GetPosts()
+		if !errors.Is(err, tc.sentinel) {
+			t.Errorf("Expected %d to match '%v', got %v", tc.statusCode, tc.sentinel, err)
+		}
+	}
+
+	c := makeFailingMockClient(404, "Not found")
+	if _, err := c.GetPosts(); errors.Is(err, ErrServerError) {
+		t.Errorf("Expected 404 not to match server error")
+	}
+}
+
+func TestErrorsAs(t *testing.T) {
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid_request", "error_description": "Missing content"}`))
+	})
+
+	_, err := c.Post("")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an *APIError, got %v", err)
+	}
+	if apiErr.StatusCode != 400 || apiErr.Method != "POST" || apiErr.URL != "https://micro.blog/micropub" {
+		t.Errorf("Unexpected error details %+v", apiErr)
+	}
+	if apiErr.ErrorCode != "invalid_request" || apiErr.ErrorDescription != "Missing content" {
+		t.Errorf("Expected JSON error to be parsed, got %+v", apiErr)
+	}
+}
+
+type failingClient struct {
+	err error
+}
+
+func (f failingClient) Do(req *http.Request) (*http.Response, error) {
+	return nil, f.err
+}
+
+func TestNetworkError(t *testing.T) {
+	connErr := errors.New("connection refused")
+	c := apiClient{httpClient: aClient{httpClient: failingClient{err: connErr}}}
+
+	_, err := c.GetPosts()
+	var netErr *NetworkError
+	if !errors.As(err, &netErr) {
+		t.Fatalf("Expected a *NetworkError, got %v", err)
+	}
+	if !errors.Is(err, connErr) {
+		t.Errorf("Expected the network error to wrap the original error")
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	c := makeMockClient("ABCD12345", "<html>not json</html>")
+	_, err := c.GetPosts()
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Errorf("Expected a *DecodeError, got %v", err)
+	}
+}
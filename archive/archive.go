@@ -0,0 +1,281 @@
+// Package archive keeps a local copy of the posts of micro.blog users.
+//
+// An Archive is stored as a single JSON file. Sync mirrors the timeline of
+// a user into it, fetching every post the first time and only the newer
+// and most recent posts after that:
+//
+//	a, err := archive.Open("posts.json")
+//	if err != nil {
+//		...
+//	}
+//	result, err := a.Sync(ctx, client, "manton", archive.SyncOptions{})
+//	if err != nil {
+//		...
+//	}
+//	err = a.Save()
+//
+// Find queries the archive and returns the posts as microdotblog.Post values.
+package archive
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	micro "github.com/fiskeben/microdotblog"
+)
+
+// formatVersion is the version of the file format written by Save.
+const formatVersion = 1
+
+// Archive holds the posts of one or more users. It is safe for
+// concurrent use.
+type Archive struct {
+	path string
+
+	mu    sync.RWMutex
+	users map[string]*userState
+	posts map[int64]*entry
+}
+
+type userState struct {
+	LastID   int64     `json:"last_id,string"`
+	LastSync time.Time `json:"last_sync"`
+}
+
+type entry struct {
+	Username string     `json:"username"`
+	Post     micro.Post `json:"post"`
+}
+
+type file struct {
+	Version int                   `json:"version"`
+	Users   map[string]*userState `json:"users"`
+	Posts   []*entry              `json:"posts"`
+}
+
+// New creates an empty archive that is saved to path.
+func New(path string) *Archive {
+	return &Archive{
+		path:  path,
+		users: map[string]*userState{},
+		posts: map[int64]*entry{},
+	}
+}
+
+// Open reads the archive stored at path. If there is no file
+// at path it returns an empty archive.
+func Open(path string) (*Archive, error) {
+	a := New(path)
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	for username, state := range f.Users {
+		a.users[username] = state
+	}
+	for _, e := range f.Posts {
+		a.posts[e.Post.ID] = e
+	}
+	return a, nil
+}
+
+// Save writes the archive to its file. The file is replaced atomically,
+// so a failed save leaves the previous version intact.
+func (a *Archive) Save() error {
+	a.mu.RLock()
+	f := file{Version: formatVersion, Users: a.users}
+	for _, e := range a.posts {
+		f.Posts = append(f.Posts, e)
+	}
+	sort.Slice(f.Posts, func(i, j int) bool { return f.Posts[i].Post.ID < f.Posts[j].Post.ID })
+	data, err := json.MarshalIndent(f, "", "  ")
+	a.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(a.path), filepath.Base(a.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), a.path)
+}
+
+// Users returns the users in the archive, sorted by username.
+func (a *Archive) Users() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var users []string
+	for username := range a.users {
+		users = append(users, username)
+	}
+	sort.Strings(users)
+	return users
+}
+
+// LastID returns the ID of the newest archived post of the user,
+// or 0 if the user has not been synced.
+func (a *Archive) LastID(username string) int64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if state, ok := a.users[username]; ok {
+		return state.LastID
+	}
+	return 0
+}
+
+// Get returns the post with the given ID.
+func (a *Archive) Get(id int64) (micro.Post, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	e, ok := a.posts[id]
+	if !ok {
+		return micro.Post{}, false
+	}
+	return e.Post, true
+}
+
+// Len returns the number of posts in the archive.
+func (a *Archive) Len() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.posts)
+}
+
+// put adds or replaces a post and reports whether it was added
+// or changed.
+func (a *Archive) put(username string, post micro.Post) (added, changed bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	state, ok := a.users[username]
+	if !ok {
+		state = &userState{}
+		a.users[username] = state
+	}
+	if post.ID > state.LastID {
+		state.LastID = post.ID
+	}
+
+	old, ok := a.posts[post.ID]
+	a.posts[post.ID] = &entry{Username: username, Post: post}
+	if !ok {
+		return true, false
+	}
+	return false, !samePost(old.Post, post)
+}
+
+// samePost reports whether two versions of a post are the same,
+// ignoring the relative date that changes all the time.
+func samePost(a, b micro.Post) bool {
+	a.MicroblogProperties.DateRelative = ""
+	b.MicroblogProperties.DateRelative = ""
+	x, errX := json.Marshal(a)
+	y, errY := json.Marshal(b)
+	return errX == nil && errY == nil && string(x) == string(y)
+}
+
+// Query selects posts in Find. The zero Query matches all posts.
+type Query struct {
+	// Username only matches posts archived for this user.
+	Username string
+	// From and To only match posts published in [From, To).
+	// Zero values leave the range open.
+	From time.Time
+	To   time.Time
+	// Text only matches posts that contain it in their title or text,
+	// ignoring case.
+	Text string
+	// Favourites only matches posts that are favourites.
+	Favourites bool
+	// Limit is the maximum number of posts to return. 0 means no limit.
+	Limit int
+}
+
+func (q Query) match(e *entry) bool {
+	p := e.Post
+	if q.Username != "" && e.Username != q.Username {
+		return false
+	}
+	if !q.From.IsZero() && p.DatePublished.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !p.DatePublished.Before(q.To) {
+		return false
+	}
+	if q.Favourites && !p.MicroblogProperties.IsFavorite {
+		return false
+	}
+	if q.Text != "" {
+		text := strings.ToLower(p.Title + "\n" + p.PlainText())
+		if !strings.Contains(text, strings.ToLower(q.Text)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Find returns the posts that match q, newest first.
+func (a *Archive) Find(q Query) []micro.Post {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var posts []micro.Post
+	for _, e := range a.posts {
+		if q.match(e) {
+			posts = append(posts, e.Post)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].DatePublished.Equal(posts[j].DatePublished) {
+			return posts[i].DatePublished.After(posts[j].DatePublished)
+		}
+		return posts[i].ID > posts[j].ID
+	})
+	if q.Limit > 0 && len(posts) > q.Limit {
+		posts = posts[:q.Limit]
+	}
+	return posts
+}
+
+// Between returns the posts published in [from, to), newest first.
+func (a *Archive) Between(from, to time.Time) []micro.Post {
+	return a.Find(Query{From: from, To: to})
+}
+
+// Search returns the posts that contain text, newest first.
+func (a *Archive) Search(text string) []micro.Post {
+	return a.Find(Query{Text: text})
+}
+
+// Favourites returns the posts that are favourites, newest first.
+func (a *Archive) Favourites() []micro.Post {
+	return a.Find(Query{Favourites: true})
+}
@@ -0,0 +1,188 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	micro "github.com/fiskeben/microdotblog"
+	"github.com/fiskeben/microdotblog/microblogtest"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestSync(t *testing.T) {
+	s := microblogtest.NewServer()
+	defer s.Close()
+	s.AddUser("ricco", "ricco-token")
+	s.AddUser("manton", "manton-token")
+	for i := 0; i < 5; i++ {
+		s.AddPost("manton", fmt.Sprintf("<p>Post %d</p>", i))
+	}
+	client := s.APIClient("ricco-token")
+	ctx := context.Background()
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "posts.json")
+	a, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := a.Sync(ctx, client, "manton", SyncOptions{PageSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 5 || result.Fetched != 5 {
+		t.Errorf("expected 5 posts to be added, got %+v", result)
+	}
+	if err := a.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	newest := s.AddPost("manton", "<p>Post 5</p>")
+
+	a, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Len() != 5 {
+		t.Fatalf("expected 5 posts after reopening, got %d", a.Len())
+	}
+
+	result, err = a.Sync(ctx, client, "manton", SyncOptions{PageSize: 2, Refresh: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 1 || result.Fetched != 1 {
+		t.Errorf("expected only the new post to be fetched, got %+v", result)
+	}
+	if a.LastID("manton") != newest {
+		t.Errorf("expected last ID %d, got %d", newest, a.LastID("manton"))
+	}
+}
+
+func TestSyncRefresh(t *testing.T) {
+	s := microblogtest.NewServer()
+	defer s.Close()
+	s.AddUser("manton", "manton-token")
+	s.AddPost("manton", "<p>Helo</p>")
+	client := s.APIClient("manton-token")
+	ctx := context.Background()
+
+	a := New("posts.json")
+	if _, err := a.Sync(ctx, client, "manton", SyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	post := a.Find(Query{})[0]
+	if err := client.UpdateEntry(post.URL, micro.ReplaceProperty("content", "<p>Hello</p>")); err != nil {
+		t.Fatal(err)
+	}
+	added := s.AddPost("manton", "<p>Another</p>")
+
+	result, err := a.Sync(ctx, client, "manton", SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 1 || result.Updated != 1 {
+		t.Errorf("expected 1 added and 1 updated post, got %+v", result)
+	}
+	if p, _ := a.Get(post.ID); p.ContentHTML != "<p>Hello</p>" {
+		t.Errorf("expected the post to be updated, got %q", p.ContentHTML)
+	}
+	if a.LastID("manton") != added {
+		t.Errorf("expected last ID %d, got %d", added, a.LastID("manton"))
+	}
+}
+
+func TestSyncFailureKeepsLastID(t *testing.T) {
+	s := microblogtest.NewServer()
+	defer s.Close()
+	s.AddUser("ricco", "ricco-token")
+	s.AddUser("manton", "manton-token")
+	s.AddPost("manton", "<p>One</p>")
+	client := s.APIClient("ricco-token", micro.WithRetryPolicy(micro.RetryPolicy{MaxAttempts: 1}))
+	ctx := context.Background()
+
+	a := New("posts.json")
+	if _, err := a.Sync(ctx, client, "manton", SyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	lastID := a.LastID("manton")
+
+	s.AddPost("manton", "<p>Two</p>")
+	s.AddPost("manton", "<p>Three</p>")
+	s.Fail(microblogtest.Failure{Method: "GET", Path: "/posts/manton", StatusCode: http.StatusInternalServerError, Times: 1})
+
+	_, err := a.Sync(ctx, client, "manton", SyncOptions{})
+	if !errors.Is(err, micro.ErrServerError) {
+		t.Fatalf("expected a server error, got %v", err)
+	}
+	if a.LastID("manton") != lastID {
+		t.Errorf("expected the last ID to stay at %d, got %d", lastID, a.LastID("manton"))
+	}
+
+	result, err := a.Sync(ctx, client, "manton", SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 2 {
+		t.Errorf("expected the 2 missing posts to be added, got %+v", result)
+	}
+}
+
+func TestFind(t *testing.T) {
+	a := New("posts.json")
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 12, 0, 0, 0, time.UTC) }
+
+	posts := []micro.Post{
+		{ID: 1, ContentHTML: "<p>Brewing <b>coffee</b></p>", DatePublished: day(1)},
+		{ID: 2, ContentHTML: "<p>Tea time</p>", DatePublished: day(2)},
+		{ID: 3, Title: "Coffee review", ContentHTML: "<p>Great beans</p>", DatePublished: day(3)},
+	}
+	posts[1].MicroblogProperties.IsFavorite = true
+	for _, p := range posts {
+		a.put("ricco", p)
+	}
+	a.put("manton", micro.Post{ID: 4, ContentHTML: "<p>Coffee</p>", DatePublished: day(4)})
+
+	ids := func(posts []micro.Post) []int64 {
+		var ids []int64
+		for _, p := range posts {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+
+	testCases := []struct {
+		name  string
+		posts []micro.Post
+		want  []int64
+	}{
+		{"all", a.Find(Query{}), []int64{4, 3, 2, 1}},
+		{"user", a.Find(Query{Username: "ricco"}), []int64{3, 2, 1}},
+		{"between", a.Between(day(2), day(4)), []int64{3, 2}},
+		{"search", a.Search("COFFEE"), []int64{4, 3, 1}},
+		{"favourites", a.Favourites(), []int64{2}},
+		{"limit", a.Find(Query{Text: "coffee", Username: "ricco", Limit: 1}), []int64{3}},
+	}
+	for _, tc := range testCases {
+		if got := ids(tc.posts); fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
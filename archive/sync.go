@@ -0,0 +1,114 @@
+package archive
+
+import (
+	"context"
+	"time"
+
+	micro "github.com/fiskeben/microdotblog"
+)
+
+// DefaultSyncRefresh is the number of newest posts Sync fetches again
+// when SyncOptions.Refresh is 0.
+const DefaultSyncRefresh = 20
+
+// SyncOptions changes how Sync fetches posts.
+type SyncOptions struct {
+	// PageSize is the number of posts fetched per request.
+	// 0 uses the server's default.
+	PageSize int
+	// Refresh is the number of newest archived posts that are fetched
+	// again to pick up changes to them. 0 uses DefaultSyncRefresh and
+	// a negative number only fetches new posts, so edits are missed.
+	Refresh int
+	// Full fetches the whole timeline again instead of only the
+	// posts that are newer than the newest archived post.
+	Full bool
+}
+
+// SyncResult tells what a sync changed.
+type SyncResult struct {
+	Username string
+	// Added is the number of posts that were not archived before.
+	Added int
+	// Updated is the number of archived posts that had changed.
+	Updated int
+	// Fetched is the number of posts fetched from the server.
+	Fetched int
+}
+
+// Sync fetches the posts of the user and stores them in the archive.
+// The first sync of a user fetches the whole timeline. Later syncs only
+// fetch posts newer than the newest archived post, plus the newest
+// opts.Refresh posts to pick up recent edits, unless opts.Full is set.
+// Edits to older posts are only seen by a full sync.
+// Call Save to write the changes to disk.
+//
+// If the sync fails, the posts fetched until then are kept and the next
+// sync fetches the rest.
+func (a *Archive) Sync(ctx context.Context, client micro.APIClient, username string, opts SyncOptions) (SyncResult, error) {
+	result := SyncResult{Username: username}
+
+	fetch := func(ctx context.Context, feedOpts ...micro.FeedOptions) (*micro.Feed, error) {
+		return client.GetUserPostsContext(ctx, username, feedOpts...)
+	}
+	add := func(posts []micro.Post) {
+		for _, post := range posts {
+			result.Fetched++
+			added, changed := a.put(username, post)
+			if added {
+				result.Added++
+			} else if changed {
+				result.Updated++
+			}
+		}
+	}
+
+	// Posts are stored as they arrive, but the newest ID is only known to
+	// be complete when the whole walk has finished, so a failed sync must
+	// not advance the user's last ID.
+	lastID := a.LastID(username)
+
+	refresh := opts.Refresh
+	if refresh == 0 {
+		refresh = DefaultSyncRefresh
+	}
+	if refresh > 0 && lastID != 0 && !opts.Full {
+		feed, err := fetch(ctx, micro.FeedOptions{Count: refresh})
+		if err != nil {
+			return result, err
+		}
+		add(feed.Items)
+		a.setLastID(username, lastID)
+	}
+
+	feedOpts := micro.FeedOptions{Count: opts.PageSize}
+	if !opts.Full {
+		feedOpts.SinceID = lastID
+	}
+
+	it := micro.NewFeedIterator(ctx, fetch, feedOpts)
+	for it.Next() {
+		add(it.Feed().Items)
+	}
+	if err := it.Err(); err != nil {
+		a.setLastID(username, lastID)
+		return result, err
+	}
+
+	a.mu.Lock()
+	if state, ok := a.users[username]; ok {
+		state.LastSync = time.Now()
+	} else {
+		a.users[username] = &userState{LastSync: time.Now()}
+	}
+	a.mu.Unlock()
+	return result, nil
+}
+
+func (a *Archive) setLastID(username string, id int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if state, ok := a.users[username]; ok {
+		state.LastID = id
+	}
+}
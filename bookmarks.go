@@ -0,0 +1,105 @@
+package microdotblog
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// TagList is a list of tags. It can be decoded both from a JSON array
+// and from the comma separated string micro.blog uses for bookmark tags.
+type TagList []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *TagList) UnmarshalJSON(data []byte) error {
+	var tags []string
+	if err := json.Unmarshal(data, &tags); err == nil {
+		*t = tags
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	*t = nil
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			*t = append(*t, tag)
+		}
+	}
+	return nil
+}
+
+func (a apiClient) GetBookmarks(opts ...FeedOptions) (*Feed, error) {
+	return a.GetBookmarksContext(context.Background(), opts...)
+}
+
+func (a apiClient) GetBookmarksContext(ctx context.Context, opts ...FeedOptions) (*Feed, error) {
+	endpoint := withFeedOptions(a.endpoint("/posts/bookmarks"), opts)
+	data, err := a.httpClient.getAndRead(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return feedFromResponse(data)
+}
+
+func (a apiClient) GetBookmarksByTag(tag string, opts ...FeedOptions) (*Feed, error) {
+	return a.GetBookmarksByTagContext(context.Background(), tag, opts...)
+}
+
+func (a apiClient) GetBookmarksByTagContext(ctx context.Context, tag string, opts ...FeedOptions) (*Feed, error) {
+	endpoint := withFeedOptions(a.endpoint("/posts/bookmarks?tag=%s", url.QueryEscape(tag)), opts)
+	data, err := a.httpClient.getAndRead(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return feedFromResponse(data)
+}
+
+func (a apiClient) IterateBookmarks(opts FeedOptions) *FeedIterator {
+	return a.IterateBookmarksContext(context.Background(), opts)
+}
+
+func (a apiClient) IterateBookmarksContext(ctx context.Context, opts FeedOptions) *FeedIterator {
+	return NewFeedIterator(ctx, a.GetBookmarksContext, opts)
+}
+
+func (a apiClient) BookmarkTags() ([]string, error) {
+	return a.BookmarkTagsContext(context.Background())
+}
+
+func (a apiClient) BookmarkTagsContext(ctx context.Context) ([]string, error) {
+	data, err := a.httpClient.getAndRead(ctx, a.endpoint("/posts/bookmarks/tags"))
+	if err != nil {
+		return nil, err
+	}
+
+	var tags = []string{}
+	if err = decode(data, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (a apiClient) Bookmark(bookmarkURL string) (*Post, error) {
+	return a.BookmarkContext(context.Background(), bookmarkURL)
+}
+
+func (a apiClient) BookmarkContext(ctx context.Context, bookmarkURL string) (*Post, error) {
+	return a.CreateEntryContext(ctx, MicropubEntry{BookmarkOf: bookmarkURL})
+}
+
+func (a apiClient) DeleteBookmark(ID int64) error {
+	return a.DeleteBookmarkContext(context.Background(), ID)
+}
+
+func (a apiClient) DeleteBookmarkContext(ctx context.Context, ID int64) error {
+	endpoint := a.endpoint("/posts/bookmarks/%d", ID)
+	if err := a.httpClient.delete(ctx, endpoint); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package microdotblog
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+const bookmarks = `
+{
+	"version": "https://jsonfeed.org/version/1",
+	"title": "Micro.blog - Bookmarks",
+	"items": [
+		{
+			"id": "1234",
+			"content_html": "<p><a href=\"https://golang.org/\">The Go Programming Language</a></p>",
+			"url": "https://golang.org/",
+			"date_published": "2018-01-02T15:04:05+00:00",
+			"tags": "go, programming",
+			"_microblog": {
+				"is_bookmark": true
+			}
+		}
+	]
+}`
+
+func TestGetBookmarks(t *testing.T) {
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/posts/bookmarks" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		if tag := r.URL.Query().Get("tag"); tag != "go lang" {
+			t.Errorf("Expected tag 'go lang', got '%s'", tag)
+		}
+		if count := r.URL.Query().Get("count"); count != "5" {
+			t.Errorf("Expected count 5, got '%s'", count)
+		}
+		w.Write([]byte(bookmarks))
+	})
+
+	feed, err := c.GetBookmarksByTag("go lang", FeedOptions{Count: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Items) != 1 {
+		t.Fatalf("Returned feed doesn't look right")
+	}
+
+	post := feed.Items[0]
+	if !post.MicroblogProperties.IsBookmark {
+		t.Error("Expected post to be a bookmark")
+	}
+	if !reflect.DeepEqual(post.Tags, TagList{"go", "programming"}) {
+		t.Errorf("Unexpected tags %v", post.Tags)
+	}
+}
+
+func TestTagListFromArray(t *testing.T) {
+	var tags TagList
+	if err := json.Unmarshal([]byte(`["go", "beer"]`), &tags); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tags, TagList{"go", "beer"}) {
+		t.Errorf("Unexpected tags %v", tags)
+	}
+}
+
+func TestBookmarkTags(t *testing.T) {
+	c := makeMockClient("ABCD12345", `["go", "programming"]`)
+	tags, err := c.BookmarkTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tags, []string{"go", "programming"}) {
+		t.Errorf("Unexpected tags %v", tags)
+	}
+}
+
+func TestBookmark(t *testing.T) {
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/micropub" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		if h := r.FormValue("h"); h != "entry" {
+			t.Errorf("Expected h=entry, got '%s'", h)
+		}
+		if bookmarkOf := r.FormValue("bookmark-of"); bookmarkOf != "https://golang.org/" {
+			t.Errorf("Expected bookmark-of, got '%s'", bookmarkOf)
+		}
+		w.Header().Set("Location", "https://micro.blog/bookmarks/1234")
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	post, err := c.Bookmark("https://golang.org/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.URL != "https://micro.blog/bookmarks/1234" {
+		t.Errorf("Unexpected URL '%s'", post.URL)
+	}
+}
+
+func TestDeleteBookmark(t *testing.T) {
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" || r.URL.Path != "/posts/bookmarks/1234" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+	})
+
+	if err := c.DeleteBookmark(1234); err != nil {
+		t.Error(err)
+	}
+}
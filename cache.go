@@ -0,0 +1,232 @@
+package microdotblog
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Cache stores responses to GET requests so that the client can send
+// conditional requests and skip downloading unchanged responses.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the response stored under key.
+	Get(key string) (CachedResponse, bool)
+	// Set stores a response under key.
+	Set(key string, response CachedResponse)
+	// Delete removes the response stored under key.
+	Delete(key string)
+}
+
+// CachedResponse is a response body along with the validators the
+// server sent for it.
+type CachedResponse struct {
+	Body         []byte    `json:"body"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Stored       time.Time `json:"stored"`
+}
+
+// hasValidators reports whether the response can be revalidated with a
+// conditional request.
+func (r CachedResponse) hasValidators() bool {
+	return r.ETag != "" || r.LastModified != ""
+}
+
+// MemoryCache is a Cache that keeps a limited number of responses in
+// memory and evicts the least recently used one when it is full.
+type MemoryCache struct {
+	mu       sync.Mutex
+	size     int
+	entries  map[string]*list.Element
+	recently *list.List
+}
+
+type memoryEntry struct {
+	key      string
+	response CachedResponse
+}
+
+// NewMemoryCache creates a cache that holds up to size responses.
+func NewMemoryCache(size int) *MemoryCache {
+	if size < 1 {
+		size = 1
+	}
+	return &MemoryCache{
+		size:     size,
+		entries:  map[string]*list.Element{},
+		recently: list.New(),
+	}
+}
+
+// Get implements Cache.
+func (c *MemoryCache) Get(key string) (CachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return CachedResponse{}, false
+	}
+	c.recently.MoveToFront(e)
+	return e.Value.(*memoryEntry).response, true
+}
+
+// Set implements Cache.
+func (c *MemoryCache) Set(key string, response CachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*memoryEntry).response = response
+		c.recently.MoveToFront(e)
+		return
+	}
+
+	c.entries[key] = c.recently.PushFront(&memoryEntry{key: key, response: response})
+	for c.recently.Len() > c.size {
+		oldest := c.recently.Back()
+		c.recently.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryEntry).key)
+	}
+}
+
+// Delete implements Cache.
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.recently.Remove(e)
+		delete(c.entries, key)
+	}
+}
+
+// Len returns the number of responses in the cache.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.recently.Len()
+}
+
+// DiskCache is a Cache that stores each response in a file in a
+// directory, so that it survives restarts. Errors reading or writing
+// files are treated as cache misses.
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache creates a cache in dir, creating the directory if needed.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+// path returns the file for key. Keys are hashed so any key is a
+// valid file name.
+func (c *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get implements Cache.
+func (c *DiskCache) Get(key string) (CachedResponse, bool) {
+	data, err := ioutil.ReadFile(c.path(key))
+	if err != nil {
+		return CachedResponse{}, false
+	}
+	var response CachedResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return CachedResponse{}, false
+	}
+	return response, true
+}
+
+// Set implements Cache.
+func (c *DiskCache) Set(key string, response CachedResponse) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+	// Write to a temporary file first so that readers never see
+	// a partly written response.
+	tmp, err := ioutil.TempFile(c.dir, "tmp-")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err != nil || closeErr != nil {
+		return
+	}
+	os.Rename(tmp.Name(), c.path(key))
+}
+
+// Delete implements Cache.
+func (c *DiskCache) Delete(key string) {
+	os.Remove(c.path(key))
+}
+
+// cacheKey returns the key for a GET request to endpoint. Responses
+// depend on who asks, so the key includes a hash of the token.
+func (a aClient) cacheKey(endpoint string) string {
+	sum := sha256.Sum256([]byte(a.token))
+	return hex.EncodeToString(sum[:8]) + " " + endpoint
+}
+
+// cachedGet sends a GET request, using the cache to send a conditional
+// request and to answer it when the server responds 304 Not Modified.
+// Responses without an ETag or Last-Modified header are served from the
+// cache until they are older than the cache TTL.
+func (a aClient) cachedGet(ctx context.Context, endpoint string) ([]byte, error) {
+	key := a.cacheKey(endpoint)
+	cached, ok := a.cache.Get(key)
+	if ok && !cached.hasValidators() && time.Since(cached.Stored) < a.cacheTTL {
+		return cached.Body, nil
+	}
+
+	r := request{method: "GET", endpoint: endpoint, idempotent: true}
+	if ok && cached.hasValidators() {
+		r.header = http.Header{}
+		if cached.ETag != "" {
+			r.header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			r.header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	res, data, err := a.send(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusNotModified {
+		cached.Stored = time.Now()
+		a.cache.Set(key, cached)
+		return cached.Body, nil
+	}
+
+	response := CachedResponse{
+		Body:         data,
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+		Stored:       time.Now(),
+	}
+	if response.hasValidators() || a.cacheTTL > 0 {
+		a.cache.Set(key, response)
+	} else if ok {
+		a.cache.Delete(key)
+	}
+	return data, nil
+}
@@ -0,0 +1,187 @@
+package microdotblog
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheETag(t *testing.T) {
+	var requests, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(posts))
+	}))
+	defer server.Close()
+
+	c := NewAPIClient("ABCD12345", WithBaseURL(server.URL), WithCache(NewMemoryCache(10), 0))
+
+	for i := 0; i < 3; i++ {
+		feed, err := c.GetUserPosts("ricco")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if feed.Title != "Micro.blog - Ricco Førgaard" {
+			t.Errorf("unexpected feed %q on request %d", feed.Title, i+1)
+		}
+	}
+	if requests != 3 || notModified != 2 {
+		t.Errorf("expected 3 requests of which 2 were not modified, got %d and %d", requests, notModified)
+	}
+}
+
+func TestCacheLastModified(t *testing.T) {
+	const lastModified = "Wed, 01 Jan 2020 12:00:00 GMT"
+	var notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte(`[{"username": "manton"}]`))
+	}))
+	defer server.Close()
+
+	c := NewAPIClient("ABCD12345", WithBaseURL(server.URL), WithCache(NewMemoryCache(10), 0))
+	for i := 0; i < 2; i++ {
+		users, err := c.Followers("ricco")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(users) != 1 || users[0].Username != "manton" {
+			t.Errorf("unexpected users %+v", users)
+		}
+	}
+	if notModified != 1 {
+		t.Errorf("expected 1 conditional request, got %d", notModified)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(posts))
+	}))
+	defer server.Close()
+
+	cache := NewMemoryCache(10)
+	c := NewAPIClient("ABCD12345", WithBaseURL(server.URL), WithCache(cache, time.Hour))
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetPosts(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request within the TTL, got %d", requests)
+	}
+
+	// Age the cached response past the TTL.
+	key := c.(apiClient).httpClient.cacheKey(server.URL + "/posts/all")
+	response, ok := cache.Get(key)
+	if !ok {
+		t.Fatal("expected the response to be cached")
+	}
+	response.Stored = time.Now().Add(-2 * time.Hour)
+	cache.Set(key, response)
+
+	if _, err := c.GetPosts(); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Errorf("expected a new request after the TTL, got %d requests", requests)
+	}
+
+	// Checks always go to the server.
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"count": 0, "check_seconds": 30}`))
+	})
+	for i := 0; i < 2; i++ {
+		if _, err := c.Check(1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if requests != 4 {
+		t.Errorf("expected checks not to be cached, got %d requests", requests)
+	}
+}
+
+func TestCacheKeyDependsOnToken(t *testing.T) {
+	a := aClient{token: "one"}
+	b := aClient{token: "two"}
+	if a.cacheKey("https://micro.blog/posts/all") == b.cacheKey("https://micro.blog/posts/all") {
+		t.Error("expected different keys for different tokens")
+	}
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewMemoryCache(2)
+	c.Set("a", CachedResponse{Body: []byte("a")})
+	c.Set("b", CachedResponse{Body: []byte("b")})
+	c.Get("a")
+	c.Set("c", CachedResponse{Body: []byte("c")})
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("expected a to be kept")
+	}
+	if c.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", c.Len())
+	}
+
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected a to be deleted")
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "microdotblog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := NewDiskCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := CachedResponse{
+		Body:         []byte(`{"items": []}`),
+		ETag:         `"abc"`,
+		LastModified: "Wed, 01 Jan 2020 12:00:00 GMT",
+		Stored:       time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	c.Set("some key/with slashes", want)
+
+	// A new cache in the same directory sees the response.
+	c, err = NewDiskCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := c.Get("some key/with slashes")
+	if !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	c.Delete("some key/with slashes")
+	if _, ok := c.Get("some key/with slashes"); ok {
+		t.Error("expected the response to be deleted")
+	}
+}
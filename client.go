@@ -2,27 +2,48 @@ package microdotblog
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// DefaultBaseURL is the server used unless WithBaseURL is given.
+const DefaultBaseURL = "https://micro.blog"
+
+// DefaultUserAgent is sent with every request unless WithUserAgent is given.
+const DefaultUserAgent = "microdotblog-go"
+
 // NewAPIClient creates a new client with a default HTTP client.
-// Pass an access token here.
-func NewAPIClient(token string) APIClient {
+// Pass an access token here and any options to change the defaults.
+func NewAPIClient(token string, opts ...Option) APIClient {
 	c := apiClient{
+		baseURL: DefaultBaseURL,
 		httpClient: aClient{
 			httpClient: http.DefaultClient,
 			token:      token,
+			userAgent:  DefaultUserAgent,
 		},
 	}
 
+	for _, opt := range opts {
+		opt(&c)
+	}
+
 	return c
 }
 
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
 type internalClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
@@ -30,56 +51,137 @@ type internalClient interface {
 type aClient struct {
 	httpClient internalClient
 	token      string
+	userAgent  string
+	timeout    time.Duration
+	retry      RetryPolicy
+	readLimit  *RateLimiter
+	writeLimit *RateLimiter
+	cache      Cache
+	cacheTTL   time.Duration
 }
 
 type apiClient struct {
-	httpClient aClient
+	httpClient    aClient
+	baseURL       string
+	lookupCreated bool
 }
 
-func (a apiClient) GetPosts() (*Feed, error) {
-	data, err := a.httpClient.getAndRead("https://micro.blog/posts/all")
+// endpoint builds the URL of an API endpoint from the configured base URL.
+func (a apiClient) endpoint(format string, args ...interface{}) string {
+	baseURL := a.baseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return baseURL + fmt.Sprintf(format, args...)
+}
+
+func (a apiClient) GetPosts(opts ...FeedOptions) (*Feed, error) {
+	return a.GetPostsContext(context.Background(), opts...)
+}
+
+func (a apiClient) GetPostsContext(ctx context.Context, opts ...FeedOptions) (*Feed, error) {
+	endpoint := withFeedOptions(a.endpoint("/posts/all"), opts)
+	data, err := a.httpClient.getAndRead(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
 	return feedFromResponse(data)
 }
 
-func (a apiClient) GetMentions() (*Feed, error) {
-	data, err := a.httpClient.getAndRead("https://micro.blog/posts/mentions")
+func (a apiClient) GetMentions(opts ...FeedOptions) (*Feed, error) {
+	return a.GetMentionsContext(context.Background(), opts...)
+}
+
+func (a apiClient) GetMentionsContext(ctx context.Context, opts ...FeedOptions) (*Feed, error) {
+	endpoint := withFeedOptions(a.endpoint("/posts/mentions"), opts)
+	data, err := a.httpClient.getAndRead(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
 	return feedFromResponse(data)
 }
 
-func (a apiClient) GetFavourites() (*Feed, error) {
-	data, err := a.httpClient.getAndRead("https://micro.blog/posts/favorites")
+func (a apiClient) GetFavourites(opts ...FeedOptions) (*Feed, error) {
+	return a.GetFavouritesContext(context.Background(), opts...)
+}
+
+func (a apiClient) GetFavouritesContext(ctx context.Context, opts ...FeedOptions) (*Feed, error) {
+	endpoint := withFeedOptions(a.endpoint("/posts/favorites"), opts)
+	data, err := a.httpClient.getAndRead(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
 	return feedFromResponse(data)
 }
 
-func (a apiClient) Discover() (*Feed, error) {
-	data, err := a.httpClient.getAndRead("https://micro.blog/posts/discover")
+func (a apiClient) Discover(opts ...FeedOptions) (*Feed, error) {
+	return a.DiscoverContext(context.Background(), opts...)
+}
+
+func (a apiClient) DiscoverContext(ctx context.Context, opts ...FeedOptions) (*Feed, error) {
+	endpoint := withFeedOptions(a.endpoint("/posts/discover"), opts)
+	data, err := a.httpClient.getAndRead(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
 	return feedFromResponse(data)
 }
 
-func (a apiClient) GetUserPosts(username string) (*Feed, error) {
-	endpoint := fmt.Sprintf("https://micro.blog/posts/%s", username)
-	data, err := a.httpClient.getAndRead(endpoint)
+func (a apiClient) GetUserPosts(username string, opts ...FeedOptions) (*Feed, error) {
+	return a.GetUserPostsContext(context.Background(), username, opts...)
+}
+
+func (a apiClient) GetUserPostsContext(ctx context.Context, username string, opts ...FeedOptions) (*Feed, error) {
+	endpoint := withFeedOptions(a.endpoint("/posts/%s", url.PathEscape(username)), opts)
+	data, err := a.httpClient.getAndRead(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
 	return feedFromResponse(data)
 }
 
+func (a apiClient) IteratePosts(opts FeedOptions) *FeedIterator {
+	return a.IteratePostsContext(context.Background(), opts)
+}
+
+func (a apiClient) IteratePostsContext(ctx context.Context, opts FeedOptions) *FeedIterator {
+	return NewFeedIterator(ctx, a.GetPostsContext, opts)
+}
+
+func (a apiClient) IterateMentions(opts FeedOptions) *FeedIterator {
+	return a.IterateMentionsContext(context.Background(), opts)
+}
+
+func (a apiClient) IterateMentionsContext(ctx context.Context, opts FeedOptions) *FeedIterator {
+	return NewFeedIterator(ctx, a.GetMentionsContext, opts)
+}
+
+func (a apiClient) IterateFavourites(opts FeedOptions) *FeedIterator {
+	return a.IterateFavouritesContext(context.Background(), opts)
+}
+
+func (a apiClient) IterateFavouritesContext(ctx context.Context, opts FeedOptions) *FeedIterator {
+	return NewFeedIterator(ctx, a.GetFavouritesContext, opts)
+}
+
+func (a apiClient) IterateUserPosts(username string, opts FeedOptions) *FeedIterator {
+	return a.IterateUserPostsContext(context.Background(), username, opts)
+}
+
+func (a apiClient) IterateUserPostsContext(ctx context.Context, username string, opts FeedOptions) *FeedIterator {
+	fetch := func(ctx context.Context, opts ...FeedOptions) (*Feed, error) {
+		return a.GetUserPostsContext(ctx, username, opts...)
+	}
+	return NewFeedIterator(ctx, fetch, opts)
+}
+
 func (a apiClient) GetConversation(ID int64) (*Feed, error) {
-	endpoint := fmt.Sprintf("https://micro.blog/posts/conversation?id=%d", ID)
-	data, err := a.httpClient.getAndRead(endpoint)
+	return a.GetConversationContext(context.Background(), ID)
+}
+
+func (a apiClient) GetConversationContext(ctx context.Context, ID int64) (*Feed, error) {
+	endpoint := a.endpoint("/posts/conversation?id=%d", ID)
+	data, err := a.httpClient.getAndRead(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -87,13 +189,18 @@ func (a apiClient) GetConversation(ID int64) (*Feed, error) {
 }
 
 func (a apiClient) Check(sinceID int64) (*Check, error) {
-	endpoint := fmt.Sprintf("https://micro.blog/posts/check?since_id=%d", sinceID)
-	data, err := a.httpClient.getAndRead(endpoint)
+	return a.CheckContext(context.Background(), sinceID)
+}
+
+func (a apiClient) CheckContext(ctx context.Context, sinceID int64) (*Check, error) {
+	endpoint := a.endpoint("/posts/check?since_id=%d", sinceID)
+	// Checks must always reach the server, or a watcher would miss posts.
+	data, err := a.httpClient.getFresh(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
 	c := &Check{}
-	err = json.Unmarshal(data, c)
+	err = decode(data, c)
 	if err != nil {
 		return nil, err
 	}
@@ -101,9 +208,13 @@ func (a apiClient) Check(sinceID int64) (*Check, error) {
 }
 
 func (a apiClient) Favourite(ID int64) error {
-	endpoint := fmt.Sprintf("https://micro.blog/posts/favorites?id=%d", ID)
+	return a.FavouriteContext(context.Background(), ID)
+}
+
+func (a apiClient) FavouriteContext(ctx context.Context, ID int64) error {
+	endpoint := a.endpoint("/posts/favorites?id=%d", ID)
 
-	_, err := a.httpClient.postAndRead(endpoint, nil)
+	_, err := a.httpClient.postAndRead(ctx, endpoint, nil)
 	if err != nil {
 		return err
 	}
@@ -112,55 +223,79 @@ func (a apiClient) Favourite(ID int64) error {
 }
 
 func (a apiClient) Unfavourite(ID int64) error {
-	endpoint := fmt.Sprintf("https://micro.blog/posts/favorites/%d", ID)
-	if err := a.httpClient.delete(endpoint); err != nil {
+	return a.UnfavouriteContext(context.Background(), ID)
+}
+
+func (a apiClient) UnfavouriteContext(ctx context.Context, ID int64) error {
+	endpoint := a.endpoint("/posts/favorites/%d", ID)
+	if err := a.httpClient.delete(ctx, endpoint); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (a apiClient) Reply(ID int64, message string) (*Post, error) {
-	endpoint := fmt.Sprintf("https://micro.blog/posts/reply")
+	return a.ReplyContext(context.Background(), ID, message)
+}
+
+func (a apiClient) ReplyContext(ctx context.Context, ID int64, message string) (*Post, error) {
+	endpoint := a.endpoint("/posts/reply")
 	data := url.Values{}
 	data.Add("id", strconv.FormatInt(ID, 10))
 	data.Add("text", message)
 
-	return a.sendPost(endpoint, data.Encode())
+	return a.sendPost(ctx, endpoint, "application/x-www-form-urlencoded", []byte(data.Encode()))
 }
 
 func (a apiClient) DeletePost(ID int64) error {
-	endpoint := fmt.Sprintf("https://micro.blog/posts/%d", ID)
-	if err := a.httpClient.delete(endpoint); err != nil {
+	return a.DeletePostContext(context.Background(), ID)
+}
+
+func (a apiClient) DeletePostContext(ctx context.Context, ID int64) error {
+	endpoint := a.endpoint("/posts/%d", ID)
+	if err := a.httpClient.delete(ctx, endpoint); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (a apiClient) Follow(username string) error {
-	endpoint := fmt.Sprintf("https://micro.blog/users/follow?username=%s", username)
-	if _, err := a.httpClient.postAndRead(endpoint, nil); err != nil {
+	return a.FollowContext(context.Background(), username)
+}
+
+func (a apiClient) FollowContext(ctx context.Context, username string) error {
+	endpoint := a.endpoint("/users/follow?username=%s", url.QueryEscape(username))
+	if _, err := a.httpClient.postAndRead(ctx, endpoint, nil); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (a apiClient) Unfollow(username string) error {
-	endpoint := fmt.Sprintf("https://micro.blog/users/unfollow?username=%s", username)
-	if _, err := a.httpClient.postAndRead(endpoint, nil); err != nil {
+	return a.UnfollowContext(context.Background(), username)
+}
+
+func (a apiClient) UnfollowContext(ctx context.Context, username string) error {
+	endpoint := a.endpoint("/users/unfollow?username=%s", url.QueryEscape(username))
+	if _, err := a.httpClient.postAndRead(ctx, endpoint, nil); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (a apiClient) Followers(username string) ([]User, error) {
-	endpoint := fmt.Sprintf("https://micro.blog/users/following/%s", username)
-	bytes, err := a.httpClient.getAndRead(endpoint)
+	return a.FollowersContext(context.Background(), username)
+}
+
+func (a apiClient) FollowersContext(ctx context.Context, username string) ([]User, error) {
+	endpoint := a.endpoint("/users/following/%s", url.PathEscape(username))
+	bytes, err := a.httpClient.getAndRead(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
 
 	var users = []User{}
-	err = json.Unmarshal(bytes, &users)
+	err = decode(bytes, &users)
 	if err != nil {
 		return nil, err
 	}
@@ -169,111 +304,378 @@ func (a apiClient) Followers(username string) ([]User, error) {
 }
 
 func (a apiClient) Post(message string) (*Post, error) {
-	endpoint := "https://micro.blog/micropub"
-
-	data := url.Values{}
-	data.Set("h", "entry")
-	data.Set("content", message)
+	return a.PostContext(context.Background(), message)
+}
 
-	return a.sendPost(endpoint, data.Encode())
+func (a apiClient) PostContext(ctx context.Context, message string) (*Post, error) {
+	return a.CreateEntryContext(ctx, MicropubEntry{Content: message})
 }
 
-func (a apiClient) sendPost(endpoint, payload string) (*Post, error) {
-	req, err := http.NewRequest("POST", endpoint, bytes.NewBufferString(payload))
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Add("Authorization", a.httpClient.token)
+func (a apiClient) CreateEntry(entry MicropubEntry) (*Post, error) {
+	return a.CreateEntryContext(context.Background(), entry)
+}
 
-	res, err := a.httpClient.httpClient.Do(req)
+func (a apiClient) CreateEntryContext(ctx context.Context, entry MicropubEntry) (*Post, error) {
+	payload, err := entry.encode()
 	if err != nil {
 		return nil, err
 	}
-	if err = newAPIError(res.StatusCode, res.Body); err != nil {
+
+	return a.sendPost(ctx, a.endpoint("/micropub"), entry.contentType(), payload)
+}
+
+func (a apiClient) UpdateEntry(postURL string, ops ...UpdateOperation) error {
+	return a.UpdateEntryContext(context.Background(), postURL, ops...)
+}
+
+func (a apiClient) UpdateEntryContext(ctx context.Context, postURL string, ops ...UpdateOperation) error {
+	body, err := updateRequest(postURL, ops)
+	if err != nil {
+		return err
+	}
+	return a.micropubAction(ctx, body)
+}
+
+func (a apiClient) DeleteEntry(postURL string) error {
+	return a.DeleteEntryContext(context.Background(), postURL)
+}
+
+func (a apiClient) DeleteEntryContext(ctx context.Context, postURL string) error {
+	return a.micropubAction(ctx, map[string]interface{}{"action": "delete", "url": postURL})
+}
+
+func (a apiClient) UndeleteEntry(postURL string) error {
+	return a.UndeleteEntryContext(context.Background(), postURL)
+}
+
+func (a apiClient) UndeleteEntryContext(ctx context.Context, postURL string) error {
+	return a.micropubAction(ctx, map[string]interface{}{"action": "undelete", "url": postURL})
+}
+
+// micropubAction sends a JSON encoded Micropub action.
+// Deleting and undeleting can safely be retried, updating can not
+// since adding values twice would duplicate them.
+func (a apiClient) micropubAction(ctx context.Context, body map[string]interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	idempotent := body["action"] != "update"
+	_, err = a.httpClient.postForLocation(ctx, a.endpoint("/micropub"), "application/json", payload, idempotent)
+	return err
+}
+
+// sendPost creates a post and returns it with the URL from the
+// Location header. The rest of the post is looked up through Micropub
+// if the client was created with WithCreatedPostLookup.
+func (a apiClient) sendPost(ctx context.Context, endpoint, contentType string, payload []byte) (*Post, error) {
+	location, err := a.httpClient.postForLocation(ctx, endpoint, contentType, payload, false)
+	if err != nil {
 		return nil, err
 	}
 
-	defer res.Body.Close()
+	if location == "" || !a.lookupCreated {
+		return &Post{URL: location}, nil
+	}
 
-	// Until Micro.blog starts returning the created post
-	// there's no need to read the response :(
-	// bytes, err := ioutil.ReadAll(res.Body)
-	// if err != nil {
-	// 	return nil, err
-	// }
+	// The post exists at this point, so a failed lookup must not look like
+	// a failed post, or callers retrying on errors would post it twice.
+	source, err := a.SourceContext(ctx, location)
+	if err != nil {
+		return &Post{URL: location}, nil
+	}
 
-	return &Post{}, nil
+	post := source.Post()
+	if post.URL == "" {
+		post.URL = location
+	}
+	return post, nil
 }
 
 func (a apiClient) PostPhoto(message string, photo Photo) (*Post, error) {
-	return &Post{}, nil
+	return a.PostPhotoContext(context.Background(), message, photo)
 }
 
-func (a aClient) getAndRead(endpoint string) ([]byte, error) {
-	req, err := http.NewRequest("GET", endpoint, nil)
+func (a apiClient) PostPhotoContext(ctx context.Context, message string, photo Photo) (*Post, error) {
+	mediaEndpoint, err := a.mediaEndpoint(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Add("Authorization", a.token)
-
-	res, err := a.httpClient.Do(req)
+	location, err := a.httpClient.upload(ctx, mediaEndpoint, photo)
 	if err != nil {
 		return nil, err
 	}
 
-	if err = newAPIError(res.StatusCode, res.Body); err != nil {
+	entry := MicropubEntry{
+		Content: message,
+		Photos:  []MicropubPhoto{{URL: location}},
+	}
+
+	return a.CreateEntryContext(ctx, entry)
+}
+
+func (a apiClient) MicropubConfig() (*MicropubConfig, error) {
+	return a.MicropubConfigContext(context.Background())
+}
+
+func (a apiClient) MicropubConfigContext(ctx context.Context) (*MicropubConfig, error) {
+	config := &MicropubConfig{}
+	if err := a.micropubQuery(ctx, url.Values{"q": {"config"}}, config); err != nil {
 		return nil, err
 	}
+	return config, nil
+}
 
-	defer res.Body.Close()
+func (a apiClient) Source(postURL string, properties ...string) (*MicropubSource, error) {
+	return a.SourceContext(context.Background(), postURL, properties...)
+}
+
+func (a apiClient) SourceContext(ctx context.Context, postURL string, properties ...string) (*MicropubSource, error) {
+	query := url.Values{}
+	query.Set("q", "source")
+	query.Set("url", postURL)
+	for _, property := range properties {
+		query.Add("properties[]", property)
+	}
+
+	source := &MicropubSource{}
+	if err := a.micropubQuery(ctx, query, source); err != nil {
+		return nil, err
+	}
+	return source, nil
+}
 
-	return ioutil.ReadAll(res.Body)
+func (a apiClient) SyndicateTargets() ([]SyndicationTarget, error) {
+	return a.SyndicateTargetsContext(context.Background())
 }
 
-func (a aClient) postAndRead(endpoint string, payload interface{}) ([]byte, error) {
-	data, err := json.Marshal(payload)
-	if err != nil {
+func (a apiClient) SyndicateTargetsContext(ctx context.Context) ([]SyndicationTarget, error) {
+	response := struct {
+		SyndicateTo []SyndicationTarget `json:"syndicate-to"`
+	}{}
+	if err := a.micropubQuery(ctx, url.Values{"q": {"syndicate-to"}}, &response); err != nil {
 		return nil, err
 	}
+	return response.SyndicateTo, nil
+}
 
-	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(data))
-	if err != nil {
+func (a apiClient) Categories() ([]string, error) {
+	return a.CategoriesContext(context.Background())
+}
+
+func (a apiClient) CategoriesContext(ctx context.Context) ([]string, error) {
+	response := struct {
+		Categories []string `json:"categories"`
+	}{}
+	if err := a.micropubQuery(ctx, url.Values{"q": {"category"}}, &response); err != nil {
 		return nil, err
 	}
+	return response.Categories, nil
+}
 
-	req.Header.Add("Authorization", a.token)
+// micropubQuery sends a Micropub GET query and decodes the response into v.
+func (a apiClient) micropubQuery(ctx context.Context, query url.Values, v interface{}) error {
+	data, err := a.httpClient.getAndRead(ctx, a.endpoint("/micropub?%s", query.Encode()))
+	if err != nil {
+		return err
+	}
+	return decode(data, v)
+}
 
-	res, err := a.httpClient.Do(req)
+// mediaEndpoint asks the Micropub server where to upload media.
+func (a apiClient) mediaEndpoint(ctx context.Context) (string, error) {
+	config, err := a.MicropubConfigContext(ctx)
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	if config.MediaEndpoint == "" {
+		return "", errors.New("the server did not advertise a media endpoint")
 	}
 
-	if err = newAPIError(res.StatusCode, res.Body); err != nil {
+	return config.MediaEndpoint, nil
+}
+
+// request describes a request to the API. The body is kept as bytes
+// so that the request can be created again when it is retried.
+type request struct {
+	method      string
+	endpoint    string
+	contentType string
+	body        []byte
+	// idempotent is set for requests that can safely be sent more than once.
+	idempotent bool
+	// header holds extra headers, like those of conditional requests.
+	header http.Header
+}
+
+// conditional reports whether r may be answered with 304 Not Modified.
+func (r request) conditional() bool {
+	return r.header.Get("If-None-Match") != "" || r.header.Get("If-Modified-Since") != ""
+}
+
+// newRequest creates the HTTP request for r. All requests go through here
+// so that every method gets the same headers.
+func (a aClient) newRequest(ctx context.Context, r request) (*http.Request, error) {
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.method, r.endpoint, body)
+	if err != nil {
 		return nil, err
 	}
 
+	for key, values := range r.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("User-Agent", a.userAgent)
+	req.Header.Set("Authorization", a.token)
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+	return req, nil
+}
+
+// send sends r and returns the successful response, whose body has been
+// read in full and closed.
+func (a aClient) send(ctx context.Context, r request) (*http.Response, []byte, error) {
+	req, res, err := a.do(ctx, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	defer res.Body.Close()
 
-	return ioutil.ReadAll(res.Body)
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, newNetworkError(req, err)
+	}
+	return res, data, nil
+}
+
+func (a aClient) getAndRead(ctx context.Context, endpoint string) ([]byte, error) {
+	if a.cache != nil {
+		return a.cachedGet(ctx, endpoint)
+	}
+	return a.getFresh(ctx, endpoint)
+}
+
+// getFresh is like getAndRead but never uses the cache.
+func (a aClient) getFresh(ctx context.Context, endpoint string) ([]byte, error) {
+	_, data, err := a.send(ctx, request{method: "GET", endpoint: endpoint, idempotent: true})
+	return data, err
 }
 
-func (a aClient) delete(endpoint string) error {
-	req, err := http.NewRequest("DELETE", endpoint, nil)
+// postAndRead is used for favourites and follows, which can safely be
+// retried since sending them twice has the same effect as sending them once.
+func (a aClient) postAndRead(ctx context.Context, endpoint string, payload interface{}) ([]byte, error) {
+	r := request{method: "POST", endpoint: endpoint, idempotent: true}
+	if payload != nil {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		r.body = body
+		r.contentType = "application/json"
+	}
+
+	_, data, err := a.send(ctx, r)
+	return data, err
+}
+
+// upload sends the photo to the media endpoint as multipart/form-data
+// and returns the URL of the uploaded file.
+func (a aClient) upload(ctx context.Context, endpoint string, photo Photo) (string, error) {
+	var data io.Reader
+	switch {
+	case photo.data != nil:
+		data = bytes.NewReader(photo.data)
+	case photo.reader == nil || photo.reader.r == nil:
+		return "", errors.New("photo has no data")
+	case photo.reader.read:
+		return "", errors.New("photo data was already read by an earlier upload")
+	default:
+		photo.reader.read = true
+		data = photo.reader.r
+	}
+
+	payload := &bytes.Buffer{}
+	writer := multipart.NewWriter(payload)
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, quoteEscaper.Replace(photo.filename)))
+	if photo.contentType != "" {
+		header.Set("Content-Type", photo.contentType)
+	}
+
+	part, err := writer.CreatePart(header)
 	if err != nil {
-		return err
+		return "", err
 	}
-	res, err := a.httpClient.Do(req)
+	if _, err = io.Copy(part, data); err != nil {
+		return "", err
+	}
+	if err = writer.Close(); err != nil {
+		return "", err
+	}
+
+	location, err := a.postForLocation(ctx, endpoint, writer.FormDataContentType(), payload.Bytes(), false)
 	if err != nil {
-		return err
+		return "", err
 	}
-	if err = newAPIError(res.StatusCode, res.Body); err != nil {
-		return err
+	if location == "" {
+		return "", errors.New("the media endpoint did not return the location of the upload")
 	}
-	return nil
+
+	return location, nil
+}
+
+// postForLocation posts the payload and returns the Location header of
+// the response, which Micropub uses to point to the created resource.
+// Pass idempotent if the request is safe to send more than once.
+func (a aClient) postForLocation(ctx context.Context, endpoint, contentType string, payload []byte, idempotent bool) (string, error) {
+	r := request{
+		method:      "POST",
+		endpoint:    endpoint,
+		contentType: contentType,
+		body:        payload,
+		idempotent:  idempotent,
+	}
+
+	res, _, err := a.send(ctx, r)
+	if err != nil {
+		return "", err
+	}
+	return res.Header.Get("Location"), nil
+}
+
+func (a aClient) delete(ctx context.Context, endpoint string) error {
+	_, _, err := a.send(ctx, request{method: "DELETE", endpoint: endpoint, idempotent: true})
+	return err
+}
+
+// limiter returns the rate limiter for requests with the given method.
+func (a aClient) limiter(method string) *RateLimiter {
+	if method == "GET" || method == "HEAD" {
+		return a.readLimit
+	}
+	return a.writeLimit
+}
+
+// withTimeout derives a context that expires after the configured timeout.
+func (a aClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if a.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, a.timeout)
 }
 
 func feedFromResponse(data []byte) (*Feed, error) {
 	f := &Feed{}
-	err := json.Unmarshal(data, f)
+	err := decode(data, f)
 	if err != nil {
 		return nil, err
 	}
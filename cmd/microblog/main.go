@@ -0,0 +1,373 @@
+// Command microblog reads and writes micro.blog from the command line.
+//
+// Usage:
+//
+//	microblog [flags] <command> [arguments]
+//
+// The commands are:
+//
+//	timeline                  show the posts of the people you follow
+//	mentions                  show posts that mention you
+//	favourites                show your favourites
+//	discover                  show curated posts
+//	user <username>           show the posts of a user
+//	conversation <id>         show a post and its replies
+//	post <text>               publish a new post
+//	reply <id> <text>         reply to a post
+//	follow <username>         start following a user
+//	unfollow <username>       stop following a user
+//	following <username>      list the users a user follows
+//	delete <id>               delete one of your posts
+//
+// The app token is read from the MICROBLOG_TOKEN environment variable or
+// from a config file with lines like "token = ..." and "base_url = ...".
+// The config file defaults to microblog/config in the user's config
+// directory and can be set with -config.
+//
+// Flags may also come after the command, as in "microblog timeline -json".
+// To post text that starts with a dash, put "--" before it.
+//
+// post and reply print the URL of the new post. With -lookup they fetch
+// the post afterwards, which is mostly useful with -json.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	micro "github.com/fiskeben/microdotblog"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, os.Getenv))
+}
+
+type config struct {
+	token   string
+	baseURL string
+}
+
+type command struct {
+	name    string
+	args    []string
+	jsonOut bool
+	count   int
+	client  micro.APIClient
+	out     io.Writer
+}
+
+// run runs the command line in args and returns the exit code.
+func run(args []string, stdout, stderr io.Writer, getenv func(string) string) int {
+	flags := flag.NewFlagSet("microblog", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: microblog [flags] <command> [arguments]")
+		fmt.Fprintln(stderr, "commands: timeline, mentions, favourites, discover, user, conversation,")
+		fmt.Fprintln(stderr, "          post, reply, follow, unfollow, following, delete")
+		flags.PrintDefaults()
+	}
+
+	jsonOut := flags.Bool("json", false, "print JSON instead of text")
+	count := flags.Int("count", 0, "number of posts to show")
+	configPath := flags.String("config", "", "path to the config file")
+	baseURL := flags.String("base-url", "", "URL of the micro.blog server")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout for each request")
+	lookup := flags.Bool("lookup", false, "look up created posts to print all their details")
+
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(args) == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	cfg, err := loadConfig(*configPath, getenv)
+	if err != nil {
+		fmt.Fprintln(stderr, "microblog:", err)
+		return 1
+	}
+	if *baseURL != "" {
+		cfg.baseURL = *baseURL
+	}
+	if cfg.token == "" {
+		fmt.Fprintln(stderr, "microblog: no token, set MICROBLOG_TOKEN or add it to the config file")
+		return 1
+	}
+
+	opts := []micro.Option{
+		micro.WithUserAgent("microblog-cli"),
+		micro.WithTimeout(*timeout),
+	}
+	if *lookup {
+		opts = append(opts, micro.WithCreatedPostLookup())
+	}
+	if cfg.baseURL != "" {
+		opts = append(opts, micro.WithBaseURL(cfg.baseURL))
+	}
+
+	cmd := command{
+		name:    args[0],
+		args:    args[1:],
+		jsonOut: *jsonOut,
+		count:   *count,
+		client:  micro.NewAPIClient(cfg.token, opts...),
+		out:     stdout,
+	}
+
+	if err := cmd.run(context.Background()); err != nil {
+		fmt.Fprintln(stderr, "microblog:", err)
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+// parseArgs parses the flags in args, which may come before, between and
+// after the other arguments, and returns the other arguments.
+// Everything after "--" is an argument, even if it starts with a dash.
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		remaining := flags.Args()
+		if n := len(args) - len(remaining); n > 0 && args[n-1] == "--" {
+			return append(rest, remaining...), nil
+		}
+		if len(remaining) == 0 {
+			return rest, nil
+		}
+		rest = append(rest, remaining[0])
+		args = remaining[1:]
+	}
+}
+
+var errUsage = errors.New("usage")
+
+func usage(format string) error {
+	return fmt.Errorf("%w: microblog %s", errUsage, format)
+}
+
+func (c command) run(ctx context.Context) error {
+	opts := micro.FeedOptions{Count: c.count}
+
+	switch c.name {
+	case "timeline":
+		if len(c.args) != 0 {
+			return usage("timeline")
+		}
+		return c.feed(c.client.GetPostsContext(ctx, opts))
+	case "mentions":
+		if len(c.args) != 0 {
+			return usage("mentions")
+		}
+		return c.feed(c.client.GetMentionsContext(ctx, opts))
+	case "favourites", "favorites":
+		if len(c.args) != 0 {
+			return usage(c.name)
+		}
+		return c.feed(c.client.GetFavouritesContext(ctx, opts))
+	case "discover":
+		if len(c.args) != 0 {
+			return usage("discover")
+		}
+		return c.feed(c.client.DiscoverContext(ctx, opts))
+	case "user":
+		if len(c.args) != 1 {
+			return usage("user <username>")
+		}
+		return c.feed(c.client.GetUserPostsContext(ctx, c.args[0], opts))
+	case "conversation":
+		if len(c.args) != 1 {
+			return usage("conversation <id>")
+		}
+		id, err := strconv.ParseInt(c.args[0], 10, 64)
+		if err != nil {
+			return usage("conversation <id>")
+		}
+		return c.feed(c.client.GetConversationContext(ctx, id))
+	case "post":
+		if len(c.args) == 0 {
+			return usage("post <text>")
+		}
+		return c.post(c.client.PostContext(ctx, strings.Join(c.args, " ")))
+	case "reply":
+		if len(c.args) < 2 {
+			return usage("reply <id> <text>")
+		}
+		id, err := strconv.ParseInt(c.args[0], 10, 64)
+		if err != nil {
+			return usage("reply <id> <text>")
+		}
+		return c.post(c.client.ReplyContext(ctx, id, strings.Join(c.args[1:], " ")))
+	case "follow":
+		if len(c.args) != 1 {
+			return usage("follow <username>")
+		}
+		return c.done(c.client.FollowContext(ctx, c.args[0]), "Following "+c.args[0])
+	case "unfollow":
+		if len(c.args) != 1 {
+			return usage("unfollow <username>")
+		}
+		return c.done(c.client.UnfollowContext(ctx, c.args[0]), "Unfollowed "+c.args[0])
+	case "following":
+		if len(c.args) != 1 {
+			return usage("following <username>")
+		}
+		return c.users(c.client.FollowersContext(ctx, c.args[0]))
+	case "delete":
+		if len(c.args) != 1 {
+			return usage("delete <id>")
+		}
+		id, err := strconv.ParseInt(c.args[0], 10, 64)
+		if err != nil {
+			return usage("delete <id>")
+		}
+		return c.done(c.client.DeletePostContext(ctx, id), "Deleted "+c.args[0])
+	}
+	return fmt.Errorf("%w: unknown command '%s'", errUsage, c.name)
+}
+
+func (c command) feed(feed *micro.Feed, err error) error {
+	if err != nil {
+		return err
+	}
+	if c.jsonOut {
+		return c.printJSON(feed)
+	}
+
+	for i, post := range feed.Items {
+		if i > 0 {
+			fmt.Fprintln(c.out)
+		}
+		printPost(c.out, post)
+	}
+	return nil
+}
+
+func (c command) post(post *micro.Post, err error) error {
+	if err != nil {
+		return err
+	}
+	if c.jsonOut {
+		return c.printJSON(post)
+	}
+	fmt.Fprintln(c.out, post.URL)
+	return nil
+}
+
+func (c command) users(users []micro.User, err error) error {
+	if err != nil {
+		return err
+	}
+	if c.jsonOut {
+		return c.printJSON(users)
+	}
+	for _, user := range users {
+		fmt.Fprintf(c.out, "@%s\t%s\n", user.Username, user.Name)
+	}
+	return nil
+}
+
+func (c command) done(err error, message string) error {
+	if err != nil {
+		return err
+	}
+	if c.jsonOut {
+		return c.printJSON(map[string]bool{"ok": true})
+	}
+	fmt.Fprintln(c.out, message)
+	return nil
+}
+
+func (c command) printJSON(v interface{}) error {
+	encoder := json.NewEncoder(c.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func printPost(w io.Writer, post micro.Post) {
+	fmt.Fprintf(w, "%s (@%s) · %s · %d\n",
+		post.Author.Name,
+		post.Author.MicroblogProperties.Username,
+		post.DatePublished.Local().Format("2006-01-02 15:04"),
+		post.ID,
+	)
+	text := post.PlainText()
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+	fmt.Fprintf(w, "  %s\n", post.URL)
+}
+
+// loadConfig reads the config file, if there is one, and lets the
+// MICROBLOG_TOKEN environment variable override its token.
+func loadConfig(path string, getenv func(string) string) (config, error) {
+	cfg := config{}
+
+	explicit := path != ""
+	if !explicit {
+		dir, err := os.UserConfigDir()
+		if err == nil {
+			path = filepath.Join(dir, "microblog", "config")
+		}
+	}
+
+	if path != "" {
+		f, err := os.Open(path)
+		switch {
+		case err == nil:
+			defer f.Close()
+			if cfg, err = parseConfig(f); err != nil {
+				return cfg, fmt.Errorf("%s: %v", path, err)
+			}
+		case explicit || !os.IsNotExist(err):
+			return cfg, err
+		}
+	}
+
+	if token := getenv("MICROBLOG_TOKEN"); token != "" {
+		cfg.token = token
+	}
+	return cfg, nil
+}
+
+func parseConfig(r io.Reader) (config, error) {
+	cfg := config{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return cfg, fmt.Errorf("line %d: expected key = value", n)
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		switch key {
+		case "token":
+			cfg.token = value
+		case "base_url":
+			cfg.baseURL = value
+		default:
+			return cfg, fmt.Errorf("line %d: unknown key '%s'", n, key)
+		}
+	}
+	return cfg, scanner.Err()
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	micro "github.com/fiskeben/microdotblog"
+	"github.com/fiskeben/microdotblog/microblogtest"
+)
+
+func runCommand(t *testing.T, server *microblogtest.Server, args ...string) (string, string, int) {
+	t.Helper()
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	getenv := func(key string) string {
+		if key == "MICROBLOG_TOKEN" {
+			return "ricco-token"
+		}
+		return ""
+	}
+
+	dir, err := ioutil.TempDir("", "microblog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	config := filepath.Join(dir, "config")
+	if err = ioutil.WriteFile(config, []byte("base_url = "+server.URL+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	args = append([]string{"-config", config}, args...)
+	code := run(args, stdout, stderr, getenv)
+	return stdout.String(), stderr.String(), code
+}
+
+func TestCommands(t *testing.T) {
+	server := microblogtest.NewServer()
+	defer server.Close()
+	server.AddUser("ricco", "ricco-token")
+	server.AddUser("manton", "manton-token")
+	server.AddPost("manton", "<p>Hello <b>world</b></p>")
+
+	if out, errOut, code := runCommand(t, server, "follow", "manton"); code != 0 || out != "Following manton\n" {
+		t.Errorf("follow failed with %d: %s%s", code, out, errOut)
+	}
+
+	out, errOut, code := runCommand(t, server, "timeline")
+	if code != 0 || !strings.Contains(out, "(@manton)") || !strings.Contains(out, "  Hello world\n") {
+		t.Errorf("timeline failed with %d: %s%s", code, out, errOut)
+	}
+
+	out, errOut, code = runCommand(t, server, "-json", "user", "manton")
+	if code != 0 {
+		t.Fatalf("user failed with %d: %s", code, errOut)
+	}
+	var feed micro.Feed
+	if err := json.Unmarshal([]byte(out), &feed); err != nil || len(feed.Items) != 1 {
+		t.Errorf("Expected a JSON feed with 1 post, got %s (%v)", out, err)
+	}
+
+	out, errOut, code = runCommand(t, server, "post", "Hello", "from", "the", "shell")
+	if code != 0 || !strings.HasPrefix(out, server.URL+"/ricco/") {
+		t.Errorf("post failed with %d: %s%s", code, out, errOut)
+	}
+	if posts := server.Posts("ricco"); len(posts) != 1 || posts[0].ContentHTML != "Hello from the shell" {
+		t.Errorf("Expected the post to be created, got %v", posts)
+	}
+
+	out, errOut, code = runCommand(t, server, "post", "Flags", "after", "text", "--json")
+	if code != 0 {
+		t.Fatalf("post failed with %d: %s", code, errOut)
+	}
+	var post micro.Post
+	if err := json.Unmarshal([]byte(out), &post); err != nil || post.URL == "" {
+		t.Errorf("Expected the post as JSON, got %s (%v)", out, err)
+	}
+	if posts := server.Posts("ricco"); len(posts) != 2 || posts[0].ContentHTML != "Flags after text" {
+		t.Errorf("Expected the flag to be left out of the post, got %v", posts)
+	}
+
+	if _, errOut, code = runCommand(t, server, "post", "--", "-1", "for", "--json"); code != 0 {
+		t.Fatalf("post failed with %d: %s", code, errOut)
+	}
+	if posts := server.Posts("ricco"); len(posts) != 3 || posts[0].ContentHTML != "-1 for --json" {
+		t.Errorf("Expected the text after -- to be posted as is, got %v", posts)
+	}
+
+	out, _, code = runCommand(t, server, "following", "ricco")
+	if code != 0 || !strings.HasPrefix(out, "@manton") {
+		t.Errorf("following failed with %d: %s", code, out)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	server := microblogtest.NewServer()
+	defer server.Close()
+	server.AddUser("ricco", "ricco-token")
+
+	if _, _, code := runCommand(t, server, "reply", "not-a-number", "hi"); code != 2 {
+		t.Errorf("Expected exit code 2 for bad arguments, got %d", code)
+	}
+	if _, _, code := runCommand(t, server, "timeline", "extra"); code != 2 {
+		t.Errorf("Expected exit code 2 for extra arguments, got %d", code)
+	}
+	if _, _, code := runCommand(t, server, "post", "hello", "-jsn"); code != 2 {
+		t.Errorf("Expected exit code 2 for unknown flags, got %d", code)
+	}
+	if _, _, code := runCommand(t, server, "nonsense"); code != 2 {
+		t.Errorf("Expected exit code 2 for unknown command, got %d", code)
+	}
+	if _, errOut, code := runCommand(t, server, "user", "nobody"); code != 1 || errOut == "" {
+		t.Errorf("Expected exit code 1 for API errors, got %d", code)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := parseConfig(strings.NewReader("# comment\ntoken = abc\nbase_url=https://example.org\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.token != "abc" || cfg.baseURL != "https://example.org" {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+
+	if _, err = parseConfig(strings.NewReader("password = secret\n")); err == nil {
+		t.Error("Expected unknown keys to be rejected")
+	}
+}
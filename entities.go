@@ -0,0 +1,165 @@
+package microdotblog
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Entities holds the mentions, links, images and hashtags found in a post.
+//
+// Offset and Length of mentions, links and hashtags are counted in
+// characters (runes) into the text returned by Post.PlainText. They are -1
+// if the entity could not be located in the text.
+type Entities struct {
+	Mentions []Mention
+	Links    []Link
+	Images   []Image
+	Hashtags []Hashtag
+}
+
+// Mention is an @-mention of a micro.blog user.
+type Mention struct {
+	// Username is the mentioned username without the @.
+	Username string
+	// URL is the link target of the mention, if it was linked.
+	URL    string
+	Offset int
+	Length int
+}
+
+// Author returns the mentioned user as an Author.
+func (m Mention) Author() Author {
+	var a Author
+	a.URL = m.URL
+	a.MicroblogProperties.Username = m.Username
+	return a
+}
+
+// Link is a link to a web page.
+type Link struct {
+	URL    string
+	Text   string
+	Offset int
+	Length int
+}
+
+// Image is an image embedded in a post.
+type Image struct {
+	URL string
+	Alt string
+}
+
+// Hashtag is a #hashtag in the text of a post.
+type Hashtag struct {
+	// Tag is the hashtag without the #.
+	Tag    string
+	Offset int
+	Length int
+}
+
+var (
+	mentionPattern = regexp.MustCompile(`(?:^|[^\w@/.])@(\w+)`)
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\w#&/])#(\p{L}[\p{L}\p{N}_]*)`)
+)
+
+// Entities extracts the mentions, links, images and hashtags in the
+// content of the post, in the order they appear.
+func (p Post) Entities() Entities {
+	var e Entities
+	text := p.PlainText()
+	// locator finds the text of each link in turn, so that a link
+	// is not matched to the text of an earlier one.
+	loc := locator{text: text}
+
+	var walk func(n *htmlNode)
+	walk = func(n *htmlNode) {
+		switch n.tag {
+		case "script", "style":
+			return
+		case "img":
+			if src := n.attrs["src"]; src != "" {
+				e.Images = append(e.Images, Image{URL: src, Alt: strings.TrimSpace(n.attrs["alt"])})
+			}
+		case "a":
+			href := n.attrs["href"]
+			if content := strings.TrimSpace(textContent(n)); isMention(content) {
+				offset, length := loc.find(content)
+				e.Mentions = append(e.Mentions, Mention{
+					Username: strings.TrimPrefix(content, "@"),
+					URL:      href,
+					Offset:   offset,
+					Length:   length,
+				})
+				return
+			}
+			if href != "" {
+				display := strings.Replace(cleanup(htmlRenderer{}.children(n)), indent, " ", -1)
+				if display == "" {
+					display = href
+				}
+				offset, length := loc.find(display)
+				e.Links = append(e.Links, Link{URL: href, Text: display, Offset: offset, Length: length})
+			}
+		}
+		for _, child := range n.children {
+			walk(child)
+		}
+	}
+	walk(parseHTML(p.ContentHTML))
+
+	// Mentions that are not linked are only found in the text.
+	for _, m := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[2]-1, m[3]
+		offset := utf8.RuneCountInString(text[:start])
+		if e.hasMentionAt(offset) {
+			continue
+		}
+		e.Mentions = append(e.Mentions, Mention{
+			Username: text[m[2]:m[3]],
+			Offset:   offset,
+			Length:   utf8.RuneCountInString(text[start:end]),
+		})
+	}
+	sort.SliceStable(e.Mentions, func(i, j int) bool {
+		return e.Mentions[i].Offset < e.Mentions[j].Offset
+	})
+
+	for _, m := range hashtagPattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[2]-1, m[3]
+		e.Hashtags = append(e.Hashtags, Hashtag{
+			Tag:    text[m[2]:m[3]],
+			Offset: utf8.RuneCountInString(text[:start]),
+			Length: utf8.RuneCountInString(text[start:end]),
+		})
+	}
+	return e
+}
+
+func (e Entities) hasMentionAt(offset int) bool {
+	for _, m := range e.Mentions {
+		if m.Offset == offset {
+			return true
+		}
+	}
+	return false
+}
+
+// locator finds successive pieces of text in a string.
+type locator struct {
+	text string
+	pos  int
+}
+
+// find returns the rune offset and length of the next occurrence
+// of s, or -1 and -1 if there is none.
+func (l *locator) find(s string) (int, int) {
+	i := strings.Index(l.text[l.pos:], s)
+	if s == "" || i < 0 {
+		return -1, -1
+	}
+	start := l.pos + i
+	l.pos = start + len(s)
+	return utf8.RuneCountInString(l.text[:start]), utf8.RuneCountInString(s)
+}
@@ -0,0 +1,71 @@
+package microdotblog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEntities(t *testing.T) {
+	post := Post{ContentHTML: `<p><a href="https://micro.blog/jean">@jean</a> have you seen <a href="https://example.com/café">this café</a>? #coffee #go_lang</p>` +
+		`<p><img src="https://example.com/latte.jpg" alt="A latte"> cc @manton, not foo@example.com or #1</p>`}
+
+	text := post.PlainText()
+	want := Entities{
+		Mentions: []Mention{
+			{Username: "jean", URL: "https://micro.blog/jean", Offset: 0, Length: 5},
+			{Username: "manton", Offset: 96, Length: 7},
+		},
+		Links: []Link{
+			{URL: "https://example.com/café", Text: "this café", Offset: 20, Length: 9},
+		},
+		Images: []Image{
+			{URL: "https://example.com/latte.jpg", Alt: "A latte"},
+		},
+		Hashtags: []Hashtag{
+			{Tag: "coffee", Offset: 58, Length: 7},
+			{Tag: "go_lang", Offset: 66, Length: 8},
+		},
+	}
+
+	got := post.Entities()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Entities() = %+v\nwant %+v\ntext %q", got, want, text)
+	}
+
+	runes := []rune(text)
+	for _, m := range got.Mentions {
+		if s := string(runes[m.Offset : m.Offset+m.Length]); s != "@"+m.Username {
+			t.Errorf("mention %s at offset %d is %q", m.Username, m.Offset, s)
+		}
+	}
+	for _, l := range got.Links {
+		if s := string(runes[l.Offset : l.Offset+l.Length]); s != l.Text {
+			t.Errorf("link %s at offset %d is %q", l.URL, l.Offset, s)
+		}
+	}
+	for _, h := range got.Hashtags {
+		if s := string(runes[h.Offset : h.Offset+h.Length]); s != "#"+h.Tag {
+			t.Errorf("hashtag %s at offset %d is %q", h.Tag, h.Offset, s)
+		}
+	}
+}
+
+func TestEntitiesRepeatedLinkText(t *testing.T) {
+	post := Post{ContentHTML: `<p>See <a href="https://a.example">here</a> and <a href="https://b.example">here</a>.</p>`}
+
+	links := post.Entities().Links
+	if len(links) != 2 {
+		t.Fatalf("expected 2 links, got %d", len(links))
+	}
+	if links[0].Offset != 4 || links[1].Offset != 33 {
+		t.Errorf("unexpected offsets %d and %d in %q", links[0].Offset, links[1].Offset, post.PlainText())
+	}
+}
+
+func TestMentionAuthor(t *testing.T) {
+	m := Mention{Username: "jean", URL: "https://micro.blog/jean"}
+	a := m.Author()
+	if a.URL != m.URL || a.MicroblogProperties.Username != "jean" {
+		t.Errorf("unexpected author %+v", a)
+	}
+}
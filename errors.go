@@ -1,31 +1,105 @@
 package microdotblog
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
+	"net/http"
 )
 
+// Sentinel errors that can be tested for with errors.Is, e.g.
+//
+//	if errors.Is(err, microdotblog.ErrNotFound) {
+//		...
+//	}
+var (
+	// ErrUnauthorized matches responses with status 401.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden matches responses with status 403.
+	ErrForbidden = errors.New("forbidden")
+	// ErrNotFound matches responses with status 404.
+	ErrNotFound = errors.New("not found")
+	// ErrRateLimited matches responses with status 429.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrClientError matches all responses with a 3xx or 4xx status.
+	ErrClientError = errors.New("client error")
+	// ErrServerError matches all responses with a 5xx status.
+	ErrServerError = errors.New("server error")
+)
+
+// APIError holds the details of an error response from the API.
+// All the error types returned for error responses wrap an *APIError,
+// so it can be extracted with errors.As.
+type APIError struct {
+	StatusCode     int
+	Method         string
+	URL            string
+	ServerResponse string
+	// ErrorCode and ErrorDescription are parsed from JSON error responses
+	// like {"error": "invalid_request", "error_description": "..."}.
+	ErrorCode        string
+	ErrorDescription string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.ErrorCode != "" {
+		msg = fmt.Sprintf("%s (%s", msg, e.ErrorCode)
+		if e.ErrorDescription != "" {
+			msg = fmt.Sprintf("%s: %s", msg, e.ErrorDescription)
+		}
+		msg += ")"
+	}
+	return msg
+}
+
+// Is reports whether the error matches one of the sentinel errors.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrClientError:
+		return e.StatusCode >= 300 && e.StatusCode < 500
+	case ErrServerError:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
 // HTTPError is used to indicate error responses from the API.
 type httpError interface {
 	error
-	withServerResponse(reason string) httpError
+	withAPIError(apiErr *APIError) httpError
 }
 
 // NotFound is returned when the API returns status 404.
 type NotFound struct {
 	msg            string
 	ServerResponse string
+	apiErr         *APIError
 }
 
 func (e NotFound) Error() string {
 	return fmt.Sprintf("%s (%s)", e.msg, e.ServerResponse)
 }
 
-func (e NotFound) withServerResponse(response string) httpError {
+// Unwrap returns the underlying *APIError.
+func (e NotFound) Unwrap() error {
+	return wrapped(e.apiErr)
+}
+
+func (e NotFound) withAPIError(apiErr *APIError) httpError {
 	return NotFound{
 		msg:            e.msg,
-		ServerResponse: response,
+		ServerResponse: apiErr.ServerResponse,
+		apiErr:         apiErr,
 	}
 }
 
@@ -33,16 +107,23 @@ func (e NotFound) withServerResponse(response string) httpError {
 type NotAuthorized struct {
 	msg            string
 	ServerResponse string
+	apiErr         *APIError
 }
 
 func (e NotAuthorized) Error() string {
 	return fmt.Sprintf("%s (%s)", e.msg, e.ServerResponse)
 }
 
-func (e NotAuthorized) withServerResponse(response string) httpError {
+// Unwrap returns the underlying *APIError.
+func (e NotAuthorized) Unwrap() error {
+	return wrapped(e.apiErr)
+}
+
+func (e NotAuthorized) withAPIError(apiErr *APIError) httpError {
 	return NotAuthorized{
 		msg:            e.msg,
-		ServerResponse: response,
+		ServerResponse: apiErr.ServerResponse,
+		apiErr:         apiErr,
 	}
 }
 
@@ -50,16 +131,23 @@ func (e NotAuthorized) withServerResponse(response string) httpError {
 type Forbidden struct {
 	msg            string
 	ServerResponse string
+	apiErr         *APIError
 }
 
 func (e Forbidden) Error() string {
 	return fmt.Sprintf("%s (%s)", e.msg, e.ServerResponse)
 }
 
-func (e Forbidden) withServerResponse(response string) httpError {
+// Unwrap returns the underlying *APIError.
+func (e Forbidden) Unwrap() error {
+	return wrapped(e.apiErr)
+}
+
+func (e Forbidden) withAPIError(apiErr *APIError) httpError {
 	return Forbidden{
 		msg:            e.msg,
-		ServerResponse: response,
+		ServerResponse: apiErr.ServerResponse,
+		apiErr:         apiErr,
 	}
 }
 
@@ -70,17 +158,24 @@ type ServerError struct {
 	msg            string
 	ServerResponse string
 	StatusCode     int
+	apiErr         *APIError
 }
 
 func (e ServerError) Error() string {
 	return fmt.Sprintf("Server error: %s (%d %s)", e.msg, e.StatusCode, e.ServerResponse)
 }
 
-func (e ServerError) withServerResponse(response string) httpError {
+// Unwrap returns the underlying *APIError.
+func (e ServerError) Unwrap() error {
+	return wrapped(e.apiErr)
+}
+
+func (e ServerError) withAPIError(apiErr *APIError) httpError {
 	return ServerError{
 		msg:            e.msg,
-		ServerResponse: response,
+		ServerResponse: apiErr.ServerResponse,
 		StatusCode:     e.StatusCode,
+		apiErr:         apiErr,
 	}
 }
 
@@ -91,21 +186,80 @@ type ClientError struct {
 	msg            string
 	ServerResponse string
 	StatusCode     int
+	apiErr         *APIError
 }
 
 func (e ClientError) Error() string {
 	return fmt.Sprintf("Server error: %s (%d %s)", e.msg, e.StatusCode, e.ServerResponse)
 }
 
-func (e ClientError) withServerResponse(response string) httpError {
+// Unwrap returns the underlying *APIError.
+func (e ClientError) Unwrap() error {
+	return wrapped(e.apiErr)
+}
+
+func (e ClientError) withAPIError(apiErr *APIError) httpError {
 	return ClientError{
 		msg:            e.msg,
 		StatusCode:     e.StatusCode,
-		ServerResponse: response,
+		ServerResponse: apiErr.ServerResponse,
+		apiErr:         apiErr,
+	}
+}
+
+// NetworkError is returned when a request could not be sent or
+// its response could not be read.
+type NetworkError struct {
+	Method string
+	URL    string
+	Err    error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Method, e.URL, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeError is returned when a response could not be decoded.
+type DecodeError struct {
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("could not decode response: %v", e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// wrapped avoids returning a typed nil from Unwrap.
+func wrapped(apiErr *APIError) error {
+	if apiErr == nil {
+		return nil
+	}
+	return apiErr
+}
+
+func newNetworkError(req *http.Request, err error) error {
+	return &NetworkError{Method: req.Method, URL: req.URL.String(), Err: err}
+}
+
+// decode unmarshals a response body into v.
+func decode(data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return &DecodeError{Err: err}
 	}
+	return nil
 }
 
-func newAPIError(status int, body io.ReadCloser) error {
+func newAPIError(req *http.Request, res *http.Response) error {
+	status := res.StatusCode
 	if status < 300 {
 		return nil
 	}
@@ -128,12 +282,27 @@ func newAPIError(status int, body io.ReadCloser) error {
 	}
 
 	if err != nil {
-		defer body.Close()
-		reason, readErr := ioutil.ReadAll(body)
+		defer res.Body.Close()
+
+		apiErr := &APIError{StatusCode: status}
+		if req != nil {
+			apiErr.Method = req.Method
+			apiErr.URL = req.URL.String()
+		}
+
+		reason, readErr := ioutil.ReadAll(res.Body)
 		if readErr == nil {
-			return err.withServerResponse(string(reason))
+			apiErr.ServerResponse = string(reason)
+			details := struct {
+				Error            string `json:"error"`
+				ErrorDescription string `json:"error_description"`
+			}{}
+			if json.Unmarshal(reason, &details) == nil {
+				apiErr.ErrorCode = details.Error
+				apiErr.ErrorDescription = details.ErrorDescription
+			}
 		}
-		return err
+		return err.withAPIError(apiErr)
 	}
 	return nil
 }
@@ -0,0 +1,140 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+
+	micro "github.com/fiskeben/microdotblog"
+)
+
+type atomFeed struct {
+	XMLName  xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	Lang     string       `xml:"xml:lang,attr,omitempty"`
+	ID       string       `xml:"id"`
+	Title    string       `xml:"title"`
+	Subtitle string       `xml:"subtitle,omitempty"`
+	Updated  string       `xml:"updated"`
+	Links    []atomLink   `xml:"link"`
+	Authors  []atomAuthor `xml:"author"`
+	Icon     string       `xml:"icon,omitempty"`
+	Logo     string       `xml:"logo,omitempty"`
+	Entries  []atomEntry  `xml:"entry"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Title  string `xml:"title,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomEntry struct {
+	Lang       string         `xml:"xml:lang,attr,omitempty"`
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published,omitempty"`
+	Links      []atomLink     `xml:"link"`
+	Authors    []atomAuthor   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// WriteAtom writes the feed as Atom 1.0.
+//
+// Atom requires a title for every entry, so posts without one get the
+// beginning of their text. Entries use the post URL as their ID.
+// Attachments are written as enclosure links.
+func WriteAtom(w io.Writer, feed *micro.Feed) error {
+	af := atomFeed{
+		Lang:     feed.Language,
+		ID:       feed.FeedURL,
+		Title:    feed.Title,
+		Subtitle: feed.Description,
+		Updated:  atomTime(feedUpdated(feed)),
+		Authors:  atomAuthors(authors(feed.Authors, feed.Author)),
+		Icon:     feed.Favicon,
+		Logo:     feed.Icon,
+	}
+	if af.ID == "" {
+		af.ID = feed.HomepageURL
+	}
+	if feed.FeedURL != "" {
+		af.Links = append(af.Links, atomLink{Href: feed.FeedURL, Rel: "self", Type: "application/atom+xml"})
+	}
+	if feed.HomepageURL != "" {
+		af.Links = append(af.Links, atomLink{Href: feed.HomepageURL, Rel: "alternate", Type: "text/html"})
+	}
+
+	for _, p := range feed.Items {
+		entry := atomEntry{
+			Lang:    p.Language,
+			ID:      p.URL,
+			Title:   postTitle(p),
+			Updated: atomTime(updated(p)),
+			Authors: atomAuthors(postAuthors(p, feed)),
+			Summary: p.Summary,
+			Content: atomContent{Type: "html", Body: p.ContentHTML},
+		}
+		if entry.ID == "" {
+			entry.ID = "urn:microblog:post:" + strconv.FormatInt(p.ID, 10)
+		}
+		if !p.DatePublished.IsZero() {
+			entry.Published = atomTime(p.DatePublished)
+		}
+		if p.URL != "" {
+			entry.Links = append(entry.Links, atomLink{Href: p.URL, Rel: "alternate", Type: "text/html"})
+		}
+		if p.ExternalURL != "" {
+			entry.Links = append(entry.Links, atomLink{Href: p.ExternalURL, Rel: "related"})
+		}
+		for _, a := range p.Attachments {
+			entry.Links = append(entry.Links, atomLink{
+				Href:   a.URL,
+				Rel:    "enclosure",
+				Type:   a.MimeType,
+				Title:  a.Title,
+				Length: a.SizeInBytes,
+			})
+		}
+		for _, tag := range p.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		af.Entries = append(af.Entries, entry)
+	}
+
+	return writeXML(w, af)
+}
+
+func atomAuthors(list []micro.Author) []atomAuthor {
+	var result []atomAuthor
+	for _, a := range list {
+		result = append(result, atomAuthor{Name: authorName(a), URI: a.URL})
+	}
+	return result
+}
+
+func atomTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Unix(0, 0)
+	}
+	return t.UTC().Format(time.RFC3339)
+}
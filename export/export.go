@@ -0,0 +1,88 @@
+// Package export writes feeds from micro.blog in other formats:
+// RSS 2.0, Atom 1.0, JSON Feed 1.1, and Markdown files with front matter
+// for static site generators like Hugo and Jekyll.
+//
+//	feed, err := client.GetUserPosts("manton")
+//	if err != nil {
+//		...
+//	}
+//	err = export.WriteAtom(os.Stdout, feed)
+package export
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	micro "github.com/fiskeben/microdotblog"
+)
+
+// titleLength is the length of titles made from the text of posts
+// for formats that require a title.
+const titleLength = 80
+
+// postTitle returns the title of the post, or the beginning of its
+// text if it has no title.
+func postTitle(p micro.Post) string {
+	if p.Title != "" {
+		return p.Title
+	}
+	text := p.ContentText
+	if text == "" {
+		text = p.PlainText()
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= titleLength {
+		return text
+	}
+	runes := []rune(text)[:titleLength-1]
+	return strings.TrimSpace(string(runes)) + "…"
+}
+
+// postAuthors returns the authors of a post, falling back to the
+// authors of the feed.
+func postAuthors(p micro.Post, feed *micro.Feed) []micro.Author {
+	if authors := authors(p.Authors, p.Author); len(authors) > 0 {
+		return authors
+	}
+	return authors(feed.Authors, feed.Author)
+}
+
+// authors combines the JSON Feed 1.1 authors list with the 1.0 author.
+func authors(list []micro.Author, single micro.Author) []micro.Author {
+	if len(list) > 0 {
+		return list
+	}
+	if single.Name != "" || single.URL != "" {
+		return []micro.Author{single}
+	}
+	return nil
+}
+
+// updated returns the time a post was last changed.
+func updated(p micro.Post) time.Time {
+	if p.DateModified != nil {
+		return *p.DateModified
+	}
+	return p.DatePublished
+}
+
+// feedUpdated returns the latest time a post in the feed was changed.
+func feedUpdated(feed *micro.Feed) time.Time {
+	var latest time.Time
+	for _, p := range feed.Items {
+		if t := updated(p); t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// authorName returns the name of an author, falling back
+// to their micro.blog username.
+func authorName(a micro.Author) string {
+	if a.Name != "" {
+		return a.Name
+	}
+	return a.MicroblogProperties.Username
+}
@@ -0,0 +1,245 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	micro "github.com/fiskeben/microdotblog"
+)
+
+func testFeed() *micro.Feed {
+	published := time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC)
+	modified := published.Add(time.Hour)
+
+	feed := &micro.Feed{
+		Version:     "https://jsonfeed.org/version/1",
+		Title:       "Ricco's posts",
+		HomepageURL: "https://ricco.example/",
+		FeedURL:     "https://ricco.example/feed.json",
+		Language:    "en",
+	}
+	feed.Author.Name = "Ricco"
+	feed.Author.URL = "https://ricco.example/"
+
+	titled := micro.Post{
+		ID:            2,
+		URL:           "https://ricco.example/2020/01/02/hello.html",
+		Title:         "Hello & welcome",
+		ContentHTML:   `<p>Hello <a href="https://micro.blog/manton">@manton</a>, see <a href="https://example.com">this</a>.</p>`,
+		DatePublished: published,
+		DateModified:  &modified,
+		Tags:          micro.TagList{"greeting", "meta"},
+		Attachments: []micro.Attachment{
+			{URL: "https://ricco.example/hello.mp3", MimeType: "audio/mpeg", SizeInBytes: 1024},
+		},
+	}
+	titled.MicroblogProperties.IsFavorite = true
+	titled.Author.MicroblogProperties.Username = "ricco"
+
+	untitled := micro.Post{
+		ID:            1,
+		URL:           "https://ricco.example/2020/01/01/note.html",
+		ContentHTML:   "<p>Just a <em>short</em> note</p>",
+		DatePublished: published.Add(-24 * time.Hour),
+	}
+	untitled.MicroblogProperties.InReplyToURL = "https://manton.example/1.html"
+	untitled.MicroblogProperties.Extra = map[string]json.RawMessage{"reactions": json.RawMessage(`3`)}
+
+	feed.Items = []micro.Post{titled, untitled}
+	return feed
+}
+
+func TestWriteRSS(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteRSS(&b, testFeed()); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Version string `xml:"version,attr"`
+		Channel struct {
+			Title string `xml:"title"`
+			Links []struct {
+				XMLName xml.Name
+				Href    string `xml:"href,attr"`
+				Value   string `xml:",chardata"`
+			} `xml:"link"`
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				Title       string   `xml:"title"`
+				Link        string   `xml:"link"`
+				GUID        string   `xml:"guid"`
+				PubDate     string   `xml:"pubDate"`
+				Description string   `xml:"description"`
+				Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+				Categories  []string `xml:"category"`
+				Enclosure   struct {
+					URL string `xml:"url,attr"`
+				} `xml:"enclosure"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, b.String())
+	}
+
+	if doc.Version != "2.0" || doc.Channel.Title != "Ricco's posts" || len(doc.Channel.Links) != 2 {
+		t.Fatalf("unexpected channel %+v", doc.Channel)
+	}
+	if link := doc.Channel.Links[0]; link.XMLName.Space != "" || link.Value != "https://ricco.example/" {
+		t.Errorf("unexpected channel link %+v", link)
+	}
+	if link := doc.Channel.Links[1]; link.XMLName.Space != "http://www.w3.org/2005/Atom" || link.Href != "https://ricco.example/feed.json" {
+		t.Errorf("unexpected self link %+v", link)
+	}
+	if doc.Channel.LastBuildDate != "Thu, 02 Jan 2020 16:04:05 +0000" {
+		t.Errorf("unexpected lastBuildDate %q", doc.Channel.LastBuildDate)
+	}
+	if len(doc.Channel.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(doc.Channel.Items))
+	}
+	item := doc.Channel.Items[0]
+	if item.Title != "Hello & welcome" || item.GUID != item.Link || item.PubDate != "Thu, 02 Jan 2020 15:04:05 +0000" {
+		t.Errorf("unexpected item %+v", item)
+	}
+	if !strings.Contains(item.Description, `<a href="https://example.com">this</a>`) {
+		t.Errorf("expected the HTML content, got %q", item.Description)
+	}
+	if item.Creator != "Ricco" || len(item.Categories) != 2 || item.Enclosure.URL != "https://ricco.example/hello.mp3" {
+		t.Errorf("unexpected item metadata %+v", item)
+	}
+	if doc.Channel.Items[1].Title != "" {
+		t.Errorf("expected no title for the second item, got %q", doc.Channel.Items[1].Title)
+	}
+}
+
+func TestWriteAtom(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteAtom(&b, testFeed()); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Updated string   `xml:"updated"`
+		Entries []struct {
+			ID        string `xml:"id"`
+			Title     string `xml:"title"`
+			Published string `xml:"published"`
+			Updated   string `xml:"updated"`
+			Author    struct {
+				Name string `xml:"name"`
+			} `xml:"author"`
+			Links []struct {
+				Href string `xml:"href,attr"`
+				Rel  string `xml:"rel,attr"`
+			} `xml:"link"`
+			Categories []struct {
+				Term string `xml:"term,attr"`
+			} `xml:"category"`
+			Content struct {
+				Type string `xml:"type,attr"`
+				Body string `xml:",chardata"`
+			} `xml:"content"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, b.String())
+	}
+
+	if doc.ID != "https://ricco.example/feed.json" || doc.Updated != "2020-01-02T16:04:05Z" {
+		t.Errorf("unexpected feed %+v", doc)
+	}
+	if len(doc.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(doc.Entries))
+	}
+	entry := doc.Entries[0]
+	if entry.Title != "Hello & welcome" || entry.Published != "2020-01-02T15:04:05Z" || entry.Updated != "2020-01-02T16:04:05Z" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if entry.Author.Name != "Ricco" || len(entry.Categories) != 2 || len(entry.Links) != 2 || entry.Links[1].Rel != "enclosure" {
+		t.Errorf("unexpected entry metadata %+v", entry)
+	}
+	if entry.Content.Type != "html" || !strings.HasPrefix(entry.Content.Body, "<p>Hello") {
+		t.Errorf("unexpected content %+v", entry.Content)
+	}
+	if title := doc.Entries[1].Title; title != "Just a short note" {
+		t.Errorf("expected a title from the text, got %q", title)
+	}
+}
+
+func TestWriteJSONFeed(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteJSONFeed(&b, testFeed()); err != nil {
+		t.Fatal(err)
+	}
+
+	var feed micro.Feed
+	if err := json.Unmarshal(b.Bytes(), &feed); err != nil {
+		t.Fatal(err)
+	}
+	if feed.Version != JSONFeedVersion || len(feed.Authors) != 1 || feed.Authors[0].Name != "Ricco" {
+		t.Errorf("unexpected feed %+v", feed)
+	}
+	if len(feed.Items) != 2 || feed.Items[0].DateModified == nil || !feed.Items[0].MicroblogProperties.IsFavorite {
+		t.Fatalf("unexpected items %+v", feed.Items)
+	}
+	if string(feed.Items[1].MicroblogProperties.Extra["reactions"]) != "3" {
+		t.Errorf("expected unknown _microblog keys to be kept, got %+v", feed.Items[1].MicroblogProperties)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := WriteMarkdown(dir, testFeed(), MarkdownOptions{Layout: "post"}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "2020-01-02-2.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `---
+id: 2
+title: "Hello & welcome"
+layout: "post"
+date: 2020-01-02T15:04:05Z
+lastmod: 2020-01-02T16:04:05Z
+author: "Ricco"
+tags:
+  - "greeting"
+  - "meta"
+microblog:
+  url: "https://ricco.example/2020/01/02/hello.html"
+  is_favourite: true
+  username: "ricco"
+---
+
+Hello @manton, see [this](https://example.com).
+`
+	if string(data) != want {
+		t.Errorf("unexpected file:\n%s\nwant:\n%s", data, want)
+	}
+
+	data, err = ioutil.ReadFile(filepath.Join(dir, "2020-01-01-1.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "in_reply_to_url: \"https://manton.example/1.html\"") ||
+		!strings.HasSuffix(string(data), "Just a *short* note\n") {
+		t.Errorf("unexpected file:\n%s", data)
+	}
+}
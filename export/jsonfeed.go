@@ -0,0 +1,31 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+
+	micro "github.com/fiskeben/microdotblog"
+)
+
+// JSONFeedVersion is the version URL written by WriteJSONFeed.
+const JSONFeedVersion = "https://jsonfeed.org/version/1.1"
+
+// WriteJSONFeed writes the feed as JSON Feed 1.1.
+//
+// The author of the feed and of each post is also written in the
+// authors list introduced in version 1.1. The _microblog extensions
+// are kept as they are.
+func WriteJSONFeed(w io.Writer, feed *micro.Feed) error {
+	out := *feed
+	out.Version = JSONFeedVersion
+	out.Authors = authors(feed.Authors, feed.Author)
+	out.Items = make([]micro.Post, len(feed.Items))
+	for i, p := range feed.Items {
+		p.Authors = authors(p.Authors, p.Author)
+		out.Items[i] = p
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
@@ -0,0 +1,147 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	micro "github.com/fiskeben/microdotblog"
+)
+
+// MarkdownOptions changes how WriteMarkdown writes files.
+type MarkdownOptions struct {
+	// FileName returns the name of the file for a post. The default
+	// is the Jekyll style "2006-01-02-<id>.md".
+	FileName func(p micro.Post) string
+	// Layout is written as the layout in the front matter, if set.
+	Layout string
+}
+
+// WriteMarkdown writes each post in the feed to a Markdown file in dir,
+// which is created if needed. Each file starts with YAML front matter
+// that both Hugo and Jekyll understand.
+func WriteMarkdown(dir string, feed *micro.Feed, opts MarkdownOptions) error {
+	fileName := opts.FileName
+	if fileName == nil {
+		fileName = defaultFileName
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, p := range feed.Items {
+		var b bytes.Buffer
+		if err := WritePostMarkdown(&b, p, feed, opts); err != nil {
+			return err
+		}
+		path := filepath.Join(dir, fileName(p))
+		if err := ioutil.WriteFile(path, b.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func defaultFileName(p micro.Post) string {
+	return fmt.Sprintf("%s-%d.md", p.DatePublished.Format("2006-01-02"), p.ID)
+}
+
+// WritePostMarkdown writes a single post as Markdown with front matter.
+// feed is used for the author when the post has none and may be nil.
+func WritePostMarkdown(w io.Writer, p micro.Post, feed *micro.Feed, opts MarkdownOptions) error {
+	if feed == nil {
+		feed = &micro.Feed{}
+	}
+
+	fm := frontMatter{}
+	fm.add("id", strconv.FormatInt(p.ID, 10))
+	if p.Title != "" {
+		fm.add("title", quote(p.Title))
+	}
+	if opts.Layout != "" {
+		fm.add("layout", quote(opts.Layout))
+	}
+	if !p.DatePublished.IsZero() {
+		fm.add("date", p.DatePublished.Format(time.RFC3339))
+	}
+	if p.DateModified != nil {
+		fm.add("lastmod", p.DateModified.Format(time.RFC3339))
+	}
+	if p.ExternalURL != "" {
+		fm.add("external_url", quote(p.ExternalURL))
+	}
+	if p.Summary != "" {
+		fm.add("summary", quote(p.Summary))
+	}
+	if p.Image != "" {
+		fm.add("image", quote(p.Image))
+	}
+	if authors := postAuthors(p, feed); len(authors) > 0 {
+		fm.add("author", quote(authorName(authors[0])))
+	}
+	if len(p.Tags) > 0 {
+		fm.list("tags", p.Tags)
+	}
+
+	props := p.MicroblogProperties
+	var mb []string
+	// The URL goes here and not in a top level url key, which Hugo
+	// would use as the permalink of the generated page.
+	if p.URL != "" {
+		mb = append(mb, "url: "+quote(p.URL))
+	}
+	if props.IsFavorite {
+		mb = append(mb, "is_favourite: true")
+	}
+	if props.IsBookmark {
+		mb = append(mb, "is_bookmark: true")
+	}
+	if props.InReplyToURL != "" {
+		mb = append(mb, "in_reply_to_url: "+quote(props.InReplyToURL))
+	}
+	if props.InReplyToID != 0 {
+		mb = append(mb, fmt.Sprintf("in_reply_to_id: %d", props.InReplyToID))
+	}
+	if username := p.Author.MicroblogProperties.Username; username != "" {
+		mb = append(mb, "username: "+quote(username))
+	}
+	if len(mb) > 0 {
+		fm.lines = append(fm.lines, "microblog:")
+		for _, line := range mb {
+			fm.lines = append(fm.lines, "  "+line)
+		}
+	}
+
+	content := p.Markdown()
+	if content == "" && p.ContentText != "" {
+		content = p.ContentText
+	}
+	_, err := fmt.Fprintf(w, "---\n%s\n---\n\n%s\n", strings.Join(fm.lines, "\n"), content)
+	return err
+}
+
+type frontMatter struct {
+	lines []string
+}
+
+func (f *frontMatter) add(key, value string) {
+	f.lines = append(f.lines, key+": "+value)
+}
+
+func (f *frontMatter) list(key string, values []string) {
+	f.lines = append(f.lines, key+":")
+	for _, v := range values {
+		f.lines = append(f.lines, "  - "+quote(v))
+	}
+}
+
+// quote returns s as a double quoted YAML string.
+func quote(s string) string {
+	return strconv.Quote(s)
+}
@@ -0,0 +1,131 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+
+	micro "github.com/fiskeben/microdotblog"
+)
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Image         *rssImage `xml:"image"`
+	AtomLink      *atomLink `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssImage struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title,omitempty"`
+	Link        string        `xml:"link,omitempty"`
+	Description rssCDATA      `xml:"description"`
+	Creators    []string      `xml:"dc:creator"`
+	Categories  []string      `xml:"category"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate,omitempty"`
+}
+
+type rssCDATA struct {
+	Text string `xml:",cdata"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// WriteRSS writes the feed as RSS 2.0.
+//
+// Posts without a title get none, as RSS allows. Authors are written as
+// dc:creator since RSS itself only has room for an email address. Only
+// the first attachment of a post is written, as an enclosure.
+func WriteRSS(w io.Writer, feed *micro.Feed) error {
+	channel := rssChannel{
+		Title:       feed.Title,
+		Link:        feed.HomepageURL,
+		Description: feed.Description,
+		Language:    feed.Language,
+	}
+	if channel.Description == "" {
+		channel.Description = feed.Title
+	}
+	if t := feedUpdated(feed); !t.IsZero() {
+		channel.LastBuildDate = t.Format(time.RFC1123Z)
+	}
+	if feed.Icon != "" {
+		channel.Image = &rssImage{URL: feed.Icon, Title: feed.Title, Link: feed.HomepageURL}
+	}
+	if feed.FeedURL != "" {
+		channel.AtomLink = &atomLink{Href: feed.FeedURL, Rel: "self", Type: "application/rss+xml"}
+	}
+
+	for _, p := range feed.Items {
+		item := rssItem{
+			Title:       p.Title,
+			Link:        p.URL,
+			Description: rssCDATA{p.ContentHTML},
+			Categories:  p.Tags,
+			GUID:        rssGUID{IsPermaLink: p.URL != "", Value: p.URL},
+		}
+		if p.URL == "" {
+			item.GUID.Value = strconv.FormatInt(p.ID, 10)
+		}
+		if !p.DatePublished.IsZero() {
+			item.PubDate = p.DatePublished.Format(time.RFC1123Z)
+		}
+		for _, a := range postAuthors(p, feed) {
+			item.Creators = append(item.Creators, authorName(a))
+		}
+		if len(p.Attachments) > 0 {
+			a := p.Attachments[0]
+			item.Enclosure = &rssEnclosure{URL: a.URL, Length: a.SizeInBytes, Type: a.MimeType}
+		}
+		channel.Items = append(channel.Items, item)
+	}
+
+	return writeXML(w, rss{
+		Version: "2.0",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: channel,
+	})
+}
+
+func writeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
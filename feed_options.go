@@ -0,0 +1,143 @@
+package microdotblog
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// FeedOptions selects a page of a feed.
+// Zero values are left out of the request.
+type FeedOptions struct {
+	// Count is the maximum number of posts to return.
+	Count int
+	// BeforeID only returns posts older than the post with this ID.
+	BeforeID int64
+	// SinceID only returns posts newer than the post with this ID.
+	SinceID int64
+}
+
+func (o FeedOptions) merge(other FeedOptions) FeedOptions {
+	if other.Count != 0 {
+		o.Count = other.Count
+	}
+	if other.BeforeID != 0 {
+		o.BeforeID = other.BeforeID
+	}
+	if other.SinceID != 0 {
+		o.SinceID = other.SinceID
+	}
+	return o
+}
+
+func (o FeedOptions) values() url.Values {
+	v := url.Values{}
+	if o.Count > 0 {
+		v.Set("count", strconv.Itoa(o.Count))
+	}
+	if o.BeforeID > 0 {
+		v.Set("before_id", strconv.FormatInt(o.BeforeID, 10))
+	}
+	if o.SinceID > 0 {
+		v.Set("since_id", strconv.FormatInt(o.SinceID, 10))
+	}
+	return v
+}
+
+// withFeedOptions adds the query parameters of opts to endpoint.
+// Later options override earlier ones.
+func withFeedOptions(endpoint string, opts []FeedOptions) string {
+	var o FeedOptions
+	for _, other := range opts {
+		o = o.merge(other)
+	}
+
+	query := o.values().Encode()
+	if query == "" {
+		return endpoint
+	}
+	if strings.Contains(endpoint, "?") {
+		return endpoint + "&" + query
+	}
+	return endpoint + "?" + query
+}
+
+// FeedFunc fetches a single page of a feed,
+// e.g. the GetPostsContext method of an APIClient.
+type FeedFunc func(ctx context.Context, opts ...FeedOptions) (*Feed, error)
+
+// FeedIterator walks a feed one page at a time, from newer to older posts.
+//
+//	it := client.IteratePosts(microdotblog.FeedOptions{Count: 50})
+//	for it.Next() {
+//		for _, post := range it.Feed().Items {
+//			...
+//		}
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type FeedIterator struct {
+	ctx   context.Context
+	fetch FeedFunc
+	opts  FeedOptions
+	feed  *Feed
+	err   error
+	done  bool
+}
+
+// NewFeedIterator creates an iterator that fetches pages with fetch,
+// starting at the page described by opts. ctx is used for every request.
+func NewFeedIterator(ctx context.Context, fetch FeedFunc, opts FeedOptions) *FeedIterator {
+	return &FeedIterator{ctx: ctx, fetch: fetch, opts: opts}
+}
+
+// Next fetches the next page. It returns false when there are
+// no more posts or when an error occurred.
+func (it *FeedIterator) Next() bool {
+	if it.done || it.err != nil {
+		return false
+	}
+
+	feed, err := it.fetch(it.ctx, it.opts)
+	if err != nil {
+		it.err = err
+		it.feed = nil
+		return false
+	}
+
+	if len(feed.Items) == 0 {
+		it.done = true
+		it.feed = nil
+		return false
+	}
+
+	oldest := feed.Items[0].ID
+	for _, post := range feed.Items[1:] {
+		if post.ID < oldest {
+			oldest = post.ID
+		}
+	}
+
+	// Stop if the server ignored before_id and returned the same page again.
+	if it.opts.BeforeID != 0 && oldest >= it.opts.BeforeID {
+		it.done = true
+		it.feed = nil
+		return false
+	}
+
+	it.feed = feed
+	it.opts.BeforeID = oldest
+	return true
+}
+
+// Feed returns the page fetched by the latest call to Next.
+func (it *FeedIterator) Feed() *Feed {
+	return it.feed
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *FeedIterator) Err() error {
+	return it.err
+}
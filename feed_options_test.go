@@ -0,0 +1,94 @@
+package microdotblog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestFeedOptionsQuery(t *testing.T) {
+	testCases := []struct {
+		endpoint string
+		opts     []FeedOptions
+		expected string
+	}{
+		{"https://micro.blog/posts/all", nil, "https://micro.blog/posts/all"},
+		{"https://micro.blog/posts/all", []FeedOptions{{Count: 10}}, "https://micro.blog/posts/all?count=10"},
+		{"https://micro.blog/posts/all", []FeedOptions{{Count: 10}, {BeforeID: 42}}, "https://micro.blog/posts/all?before_id=42&count=10"},
+		{"https://micro.blog/posts/check?x=1", []FeedOptions{{SinceID: 7}}, "https://micro.blog/posts/check?x=1&since_id=7"},
+	}
+
+	for _, tc := range testCases {
+		if actual := withFeedOptions(tc.endpoint, tc.opts); actual != tc.expected {
+			t.Errorf("Expected '%s', got '%s'", tc.expected, actual)
+		}
+	}
+}
+
+func TestIteratePosts(t *testing.T) {
+	var requests []string
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RawQuery)
+
+		beforeID, _ := strconv.ParseInt(r.URL.Query().Get("before_id"), 10, 64)
+		if beforeID == 0 {
+			beforeID = 10
+		}
+
+		items := ""
+		for id := beforeID - 1; id > beforeID-4 && id > 0; id-- {
+			if items != "" {
+				items += ","
+			}
+			items += fmt.Sprintf(`{"id":"%d"}`, id)
+		}
+		fmt.Fprintf(w, `{"items":[%s]}`, items)
+	})
+
+	it := c.IteratePosts(FeedOptions{Count: 3})
+	var ids []int64
+	for it.Next() {
+		for _, post := range it.Feed().Items {
+			ids = append(ids, post.ID)
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ids) != 9 || ids[0] != 9 || ids[8] != 1 {
+		t.Errorf("Expected posts 9 to 1, got %v", ids)
+	}
+	if len(requests) != 4 || requests[1] != "before_id=7&count=3" {
+		t.Errorf("Unexpected requests %v", requests)
+	}
+}
+
+func TestIteratorStopsOnError(t *testing.T) {
+	c := makeFailingMockClient(500, "Internal server error")
+	it := c.IteratePosts(FeedOptions{})
+	if it.Next() {
+		t.Error("Expected Next to return false")
+	}
+	if _, ok := it.Err().(ServerError); !ok {
+		t.Errorf("Expected server error, got %v", it.Err())
+	}
+}
+
+func TestIteratePostsContext(t *testing.T) {
+	type key struct{}
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		if r.Context().Value(key{}) != "iterate" {
+			t.Error("Expected the iterator's context on the request")
+		}
+		fmt.Fprint(w, `{"items":[{"id":"1"}]}`)
+	})
+
+	ctx := context.WithValue(context.Background(), key{}, "iterate")
+	it := c.IteratePostsContext(ctx, FeedOptions{})
+	if !it.Next() {
+		t.Fatal(it.Err())
+	}
+}
@@ -0,0 +1,163 @@
+package microdotblog
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultSyncConcurrency is how many follow and unfollow requests
+// SyncFollowing sends at the same time unless told otherwise.
+const DefaultSyncConcurrency = 4
+
+// SyncFollowingOptions changes how SyncFollowing works.
+type SyncFollowingOptions struct {
+	// Concurrency is the maximum number of requests sent at the same
+	// time. Defaults to DefaultSyncConcurrency.
+	Concurrency int
+	// KeepOthers leaves the users that are followed but not in the
+	// desired list alone instead of unfollowing them.
+	KeepOthers bool
+	// DryRun reports what would change without changing anything.
+	DryRun bool
+}
+
+// FollowAction is what SyncFollowing did for a user.
+type FollowAction string
+
+const (
+	// Followed means the user was followed.
+	Followed FollowAction = "follow"
+	// Unfollowed means the user was unfollowed.
+	Unfollowed FollowAction = "unfollow"
+	// Unchanged means the user was already followed.
+	Unchanged FollowAction = "unchanged"
+)
+
+// FollowResult is the outcome of syncing one user.
+type FollowResult struct {
+	Username string
+	Action   FollowAction
+	// Err is set if the follow or unfollow request failed.
+	Err error
+}
+
+// FollowReport lists the outcome for every user SyncFollowing looked at,
+// sorted by username.
+type FollowReport struct {
+	Results []FollowResult
+	// DryRun is set when nothing was changed because of DryRun.
+	DryRun bool
+}
+
+// Failed returns the results with an error.
+func (r FollowReport) Failed() []FollowResult {
+	var failed []FollowResult
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Count returns the number of successful results with the given action.
+func (r FollowReport) Count(action FollowAction) int {
+	n := 0
+	for _, result := range r.Results {
+		if result.Action == action && result.Err == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// SyncFollowing makes the user with the given username, who must be the
+// user of the client, follow exactly the users in desired. It compares
+// the list with the users they already follow and follows and unfollows
+// users concurrently.
+//
+// Usernames are compared ignoring case and a leading @. The returned
+// error is only set when the current list could not be fetched; errors
+// for single users are in the report.
+func SyncFollowing(ctx context.Context, client APIClient, username string, desired []string, opts SyncFollowingOptions) (FollowReport, error) {
+	report := FollowReport{DryRun: opts.DryRun}
+
+	current, err := client.FollowersContext(ctx, username)
+	if err != nil {
+		return report, err
+	}
+
+	following := map[string]string{}
+	for _, u := range current {
+		following[normalizeUsername(u.Username)] = u.Username
+	}
+
+	wanted := map[string]string{}
+	for _, name := range desired {
+		key := normalizeUsername(name)
+		if key == "" || key == normalizeUsername(username) {
+			continue
+		}
+		wanted[key] = strings.TrimPrefix(strings.TrimSpace(name), "@")
+	}
+
+	for key, name := range wanted {
+		action := Followed
+		if _, ok := following[key]; ok {
+			action = Unchanged
+		}
+		report.Results = append(report.Results, FollowResult{Username: name, Action: action})
+	}
+	if !opts.KeepOthers {
+		for key, name := range following {
+			if _, ok := wanted[key]; !ok {
+				report.Results = append(report.Results, FollowResult{Username: name, Action: Unfollowed})
+			}
+		}
+	}
+	sort.Slice(report.Results, func(i, j int) bool {
+		return strings.ToLower(report.Results[i].Username) < strings.ToLower(report.Results[j].Username)
+	})
+
+	if opts.DryRun {
+		return report, nil
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = DefaultSyncConcurrency
+	}
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range report.Results {
+		result := &report.Results[i]
+		if result.Action == Unchanged {
+			continue
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			result.Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if result.Action == Followed {
+				result.Err = client.FollowContext(ctx, result.Username)
+			} else {
+				result.Err = client.UnfollowContext(ctx, result.Username)
+			}
+		}()
+	}
+	wg.Wait()
+	return report, nil
+}
+
+func normalizeUsername(name string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "@"))
+}
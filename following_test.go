@@ -0,0 +1,191 @@
+package microdotblog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// followServer keeps the users one user follows.
+type followServer struct {
+	mu        sync.Mutex
+	following map[string]bool
+	fail      string
+}
+
+func (s *followServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	username := r.URL.Query().Get("username")
+	switch r.URL.Path {
+	case "/users/following/ricco":
+		var users []User
+		for name := range s.following {
+			users = append(users, User{Username: name})
+		}
+		json.NewEncoder(w).Encode(users)
+	case "/users/follow":
+		if username == s.fail {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		s.following[username] = true
+	case "/users/unfollow":
+		delete(s.following, username)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *followServer) list() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var names []string
+	for name := range s.following {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestSyncFollowing(t *testing.T) {
+	s := &followServer{following: map[string]bool{"manton": true, "jean": true}}
+	c := makeHandlerClient("ABCD12345", s.handle)
+
+	desired := []string{"@Manton", "amy", "bob", " ricco ", "", "amy"}
+	report, err := SyncFollowing(context.Background(), c, "ricco", desired, SyncFollowingOptions{Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []FollowResult{
+		{Username: "amy", Action: Followed},
+		{Username: "bob", Action: Followed},
+		{Username: "jean", Action: Unfollowed},
+		{Username: "Manton", Action: Unchanged},
+	}
+	if !reflect.DeepEqual(report.Results, want) {
+		t.Errorf("expected %+v, got %+v", want, report.Results)
+	}
+	if got := s.list(); !reflect.DeepEqual(got, []string{"amy", "bob", "manton"}) {
+		t.Errorf("unexpected following list %v", got)
+	}
+	if report.Count(Followed) != 2 || report.Count(Unfollowed) != 1 {
+		t.Errorf("unexpected counts in %+v", report)
+	}
+}
+
+func TestSyncFollowingDryRunAndKeepOthers(t *testing.T) {
+	s := &followServer{following: map[string]bool{"jean": true}}
+	c := makeHandlerClient("ABCD12345", s.handle)
+
+	report, err := SyncFollowing(context.Background(), c, "ricco", []string{"amy"}, SyncFollowingOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.DryRun || len(report.Results) != 2 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if got := s.list(); !reflect.DeepEqual(got, []string{"jean"}) {
+		t.Errorf("expected no changes in a dry run, got %v", got)
+	}
+
+	report, err = SyncFollowing(context.Background(), c, "ricco", []string{"amy"}, SyncFollowingOptions{KeepOthers: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 1 || report.Results[0].Action != Followed {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if got := s.list(); !reflect.DeepEqual(got, []string{"amy", "jean"}) {
+		t.Errorf("unexpected following list %v", got)
+	}
+}
+
+func TestSyncFollowingReportsErrors(t *testing.T) {
+	s := &followServer{following: map[string]bool{}, fail: "nobody"}
+	c := makeHandlerClient("ABCD12345", s.handle)
+
+	report, err := SyncFollowing(context.Background(), c, "ricco", []string{"amy", "nobody"}, SyncFollowingOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := report.Failed()
+	if len(failed) != 1 || failed[0].Username != "nobody" || !errors.Is(failed[0].Err, ErrNotFound) {
+		t.Errorf("unexpected failures %+v", failed)
+	}
+	if got := s.list(); !reflect.DeepEqual(got, []string{"amy"}) {
+		t.Errorf("unexpected following list %v", got)
+	}
+
+	_, err = SyncFollowing(context.Background(), c, "someone", nil, SyncFollowingOptions{})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the list lookup to fail, got %v", err)
+	}
+}
+
+func TestReadFollowList(t *testing.T) {
+	testCases := []struct {
+		name   string
+		format FollowListFormat
+		input  string
+	}{
+		{"text", TextFollowList, "# team\n@manton\n\n  jean  \namy\n"},
+		{"csv with header", CSVFollowList, "name,username\nManton,manton\nJean,@jean\nAmy,amy\n"},
+		{"csv without header", CSVFollowList, "manton\njean,Jean\namy\n"},
+		{"opml", OPMLFollowList, `<?xml version="1.0"?>
+<opml version="2.0">
+  <head><title>Team</title></head>
+  <body>
+    <outline text="@manton"/>
+    <outline text="Friends">
+      <outline text="Jean" htmlUrl="https://micro.blog/jean"/>
+      <outline text="Amy" htmlUrl="https://micro.blog/amy/"/>
+      <outline text="Some blog" htmlUrl="https://example.com/"/>
+    </outline>
+  </body>
+</opml>`},
+	}
+
+	want := []string{"manton", "jean", "amy"}
+	for _, tc := range testCases {
+		got, err := ReadFollowList(strings.NewReader(tc.input), tc.format)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, want, got)
+		}
+	}
+}
+
+func TestWriteFollowListRoundTrip(t *testing.T) {
+	users := []User{
+		{Username: "manton", Name: "Manton Reece", URL: "https://manton.org/"},
+		{Username: "jean", Name: "Jean", URL: "https://jean.example/"},
+	}
+
+	for _, format := range []FollowListFormat{TextFollowList, CSVFollowList, OPMLFollowList} {
+		var b bytes.Buffer
+		if err := WriteFollowList(&b, format, users); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadFollowList(&b, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, []string{"manton", "jean"}) {
+			t.Errorf("format %d: unexpected usernames %v", format, got)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package microdotblog
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// FollowListFormat is a file format for lists of users.
+type FollowListFormat int
+
+const (
+	// TextFollowList has one username per line. Blank lines and lines
+	// starting with # are ignored.
+	TextFollowList FollowListFormat = iota
+	// CSVFollowList has the username in the first column, or in the
+	// column named "username" if there is a header row.
+	CSVFollowList
+	// OPMLFollowList is an OPML outline with one entry per user.
+	OPMLFollowList
+)
+
+// ReadFollowList reads the usernames in a list, e.g. for SyncFollowing.
+// A leading @ is removed from the usernames.
+func ReadFollowList(r io.Reader, format FollowListFormat) ([]string, error) {
+	switch format {
+	case TextFollowList:
+		return readTextFollowList(r)
+	case CSVFollowList:
+		return readCSVFollowList(r)
+	case OPMLFollowList:
+		return readOPMLFollowList(r)
+	}
+	return nil, fmt.Errorf("unknown follow list format %d", format)
+}
+
+// WriteFollowList writes the users in a list, e.g. the result of Followers.
+func WriteFollowList(w io.Writer, format FollowListFormat, users []User) error {
+	switch format {
+	case TextFollowList:
+		return writeTextFollowList(w, users)
+	case CSVFollowList:
+		return writeCSVFollowList(w, users)
+	case OPMLFollowList:
+		return writeOPMLFollowList(w, users)
+	}
+	return fmt.Errorf("unknown follow list format %d", format)
+}
+
+func cleanUsername(name string) string {
+	return strings.TrimPrefix(strings.TrimSpace(name), "@")
+}
+
+func readTextFollowList(r io.Reader) ([]string, error) {
+	var usernames []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		usernames = append(usernames, cleanUsername(line))
+	}
+	return usernames, scanner.Err()
+}
+
+func writeTextFollowList(w io.Writer, users []User) error {
+	for _, u := range users {
+		if _, err := fmt.Fprintln(w, u.Username); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readCSVFollowList(r io.Reader) ([]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	column := 0
+	if len(records) > 0 {
+		for i, name := range records[0] {
+			if strings.EqualFold(strings.TrimSpace(name), "username") {
+				column = i
+				records = records[1:]
+				break
+			}
+		}
+	}
+
+	var usernames []string
+	for _, record := range records {
+		if column >= len(record) {
+			continue
+		}
+		if name := cleanUsername(record[column]); name != "" {
+			usernames = append(usernames, name)
+		}
+	}
+	return usernames, nil
+}
+
+func writeCSVFollowList(w io.Writer, users []User) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"username", "name", "url"})
+	for _, u := range users {
+		cw.Write([]string{u.Username, u.Name, u.URL})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type opml struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr"`
+	Title   string        `xml:"head>title"`
+	Body    []opmlOutline `xml:"body>outline"`
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// readOPMLFollowList takes the username from outlines with a text
+// starting with @, and otherwise from the path of their micro.blog
+// htmlUrl. Nested outlines are read too.
+func readOPMLFollowList(r io.Reader) ([]string, error) {
+	var doc opml
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var usernames []string
+	var walk func(outlines []opmlOutline)
+	walk = func(outlines []opmlOutline) {
+		for _, o := range outlines {
+			if name := opmlUsername(o); name != "" {
+				usernames = append(usernames, name)
+			}
+			walk(o.Outlines)
+		}
+	}
+	walk(doc.Body)
+	return usernames, nil
+}
+
+func opmlUsername(o opmlOutline) string {
+	if strings.HasPrefix(o.Text, "@") {
+		return cleanUsername(o.Text)
+	}
+	u, err := url.Parse(o.HTMLURL)
+	if err != nil || !strings.HasSuffix(u.Host, "micro.blog") {
+		return ""
+	}
+	return strings.Trim(u.Path, "/")
+}
+
+func writeOPMLFollowList(w io.Writer, users []User) error {
+	doc := opml{Version: "2.0", Title: "Following"}
+	for _, u := range users {
+		doc.Body = append(doc.Body, opmlOutline{
+			Text:    "@" + u.Username,
+			Title:   u.Name,
+			Type:    "link",
+			HTMLURL: DefaultBaseURL + "/" + u.Username,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
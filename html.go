@@ -0,0 +1,220 @@
+package microdotblog
+
+import (
+	"html"
+	"strings"
+)
+
+// htmlNode is a node in the small HTML tree built from post content.
+// Text nodes have an empty tag.
+type htmlNode struct {
+	tag      string
+	text     string
+	attrs    map[string]string
+	children []*htmlNode
+	parent   *htmlNode
+}
+
+// voidElements never have children or an end tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// rawTextElements contain text that is not parsed as HTML.
+var rawTextElements = map[string]bool{"script": true, "style": true}
+
+// blockElements close an open paragraph when they start.
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"div": true, "dl": true, "figure": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hr": true, "ol": true, "p": true, "pre": true,
+	"section": true, "table": true, "ul": true,
+}
+
+// parseHTML parses an HTML fragment into a tree. It is forgiving in the
+// way browsers are: unclosed elements are closed implicitly, stray end
+// tags are ignored, and entities are decoded.
+func parseHTML(s string) *htmlNode {
+	root := &htmlNode{tag: "#root"}
+	current := root
+
+	appendChild := func(n *htmlNode) {
+		n.parent = current
+		current.children = append(current.children, n)
+	}
+	appendText := func(text string) {
+		if text == "" {
+			return
+		}
+		appendChild(&htmlNode{text: html.UnescapeString(text)})
+	}
+	// closeTag closes the innermost open element with the given tag,
+	// along with everything opened inside it.
+	closeTag := func(tag string, stopAt ...string) {
+		for n := current; n != root; n = n.parent {
+			for _, stop := range stopAt {
+				if n.tag == stop {
+					return
+				}
+			}
+			if n.tag == tag {
+				current = n.parent
+				return
+			}
+		}
+	}
+
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			appendText(s)
+			break
+		}
+		appendText(s[:i])
+		s = s[i:]
+
+		switch {
+		case strings.HasPrefix(s, "<!--"):
+			end := strings.Index(s, "-->")
+			if end < 0 {
+				return root
+			}
+			s = s[end+3:]
+		case strings.HasPrefix(s, "</"):
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				appendText(s)
+				return root
+			}
+			tag := strings.ToLower(strings.TrimSpace(s[2:end]))
+			s = s[end+1:]
+			closeTag(tag)
+		case len(s) > 1 && (isLetter(s[1]) || s[1] == '!'):
+			tag, attrs, selfClosing, rest, ok := parseTag(s)
+			if !ok {
+				appendText(s)
+				return root
+			}
+			s = rest
+			if tag == "" {
+				// A doctype or other declaration.
+				continue
+			}
+
+			if blockElements[tag] {
+				closeTag("p", "blockquote", "li", "div")
+			}
+			if tag == "li" {
+				closeTag("li", "ul", "ol")
+			}
+
+			n := &htmlNode{tag: tag, attrs: attrs}
+			appendChild(n)
+
+			if rawTextElements[tag] {
+				end := strings.Index(strings.ToLower(s), "</"+tag)
+				if end < 0 {
+					end = len(s)
+				}
+				n.children = append(n.children, &htmlNode{text: s[:end], parent: n})
+				s = s[end:]
+				if close := strings.IndexByte(s, '>'); close >= 0 {
+					s = s[close+1:]
+				}
+				continue
+			}
+
+			if !voidElements[tag] && !selfClosing {
+				current = n
+			}
+		default:
+			appendText("<")
+			s = s[1:]
+		}
+	}
+	return root
+}
+
+// parseTag parses the start tag at the beginning of s. Declarations like
+// <!DOCTYPE html> are returned with an empty tag.
+func parseTag(s string) (tag string, attrs map[string]string, selfClosing bool, rest string, ok bool) {
+	if strings.HasPrefix(s, "<!") {
+		end := strings.IndexByte(s, '>')
+		if end < 0 {
+			return "", nil, false, "", false
+		}
+		return "", nil, false, s[end+1:], true
+	}
+
+	i := 1
+	for i < len(s) && !isSpace(s[i]) && s[i] != '>' && s[i] != '/' {
+		i++
+	}
+	tag = strings.ToLower(s[1:i])
+	attrs = map[string]string{}
+
+	for i < len(s) {
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			break
+		}
+		if s[i] == '>' {
+			return tag, attrs, selfClosing, s[i+1:], true
+		}
+		if s[i] == '/' {
+			selfClosing = true
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(s) && !isSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		name := strings.ToLower(s[start:i])
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+
+		value := ""
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isSpace(s[i]) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				quote := s[i]
+				end := strings.IndexByte(s[i+1:], quote)
+				if end < 0 {
+					return "", nil, false, "", false
+				}
+				value = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start := i
+				for i < len(s) && !isSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				value = s[start:i]
+			}
+		}
+		if name != "" {
+			selfClosing = false
+			attrs[name] = html.UnescapeString(value)
+		}
+	}
+	return "", nil, false, "", false
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
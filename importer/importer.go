@@ -0,0 +1,234 @@
+// Package importer moves posts from other platforms to micro.blog.
+//
+// ReadMastodon, ReadTwitter and ReadWordPress parse archives into Items.
+// An Importer publishes them through Micropub with their original dates:
+//
+//	items, err := importer.ReadMastodon(file)
+//	if err != nil {
+//		...
+//	}
+//	im := importer.Importer{Client: client, Checkpoint: "import.json"}
+//	report, err := im.Run(ctx, items)
+//	report.WriteCSV(os.Stdout)
+//
+// With a checkpoint file, an import that fails or is stopped can be run
+// again and continues where it left off.
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	micro "github.com/fiskeben/microdotblog"
+)
+
+// Item is a post read from an archive.
+type Item struct {
+	// Source is the platform the post is from, e.g. "mastodon".
+	Source string
+	// SourceID identifies the post on its platform.
+	SourceID string
+	// SourceURL is the address of the post on its platform, if known.
+	SourceURL string
+	// InReplyTo is the address of the post this is a reply to, if any.
+	InReplyTo string
+	// Entry is the post to create on micro.blog.
+	Entry micro.MicropubEntry
+	// Warnings describe the parts of the post that could not be imported,
+	// like media files that are only in the archive.
+	Warnings []string
+}
+
+// key identifies the item in checkpoints.
+func (i Item) key() string {
+	return i.Source + ":" + i.SourceID
+}
+
+// Importer publishes items to micro.blog.
+type Importer struct {
+	Client micro.APIClient
+	// DryRun reports what would be published without publishing it.
+	DryRun bool
+	// Checkpoint is the path of a file that records the items that were
+	// published. Items found in it are skipped. It is not written in
+	// dry-run mode.
+	Checkpoint string
+	// SkipReplies leaves out the items that are replies.
+	SkipReplies bool
+	// OnResult is called after each item, e.g. to show progress.
+	OnResult func(Result)
+}
+
+// Result is the outcome of importing one item.
+type Result struct {
+	Source    string
+	SourceID  string
+	SourceURL string
+	// URL is the address of the new post on micro.blog. It is empty in
+	// dry-run mode and when the item failed.
+	URL string
+	// Skipped is set for items that were published in an earlier run
+	// or left out because they are replies.
+	Skipped bool
+	// DryRun is set when the item was not published because of DryRun.
+	DryRun   bool
+	Warnings []string
+	Err      error
+}
+
+// key matches the key of the item the result is for.
+func (r Result) key() string {
+	return r.Source + ":" + r.SourceID
+}
+
+// Report lists the results of an import in the order the items were
+// handled.
+type Report struct {
+	Results []Result
+}
+
+// Mapping returns the URLs of the new posts keyed by source and source
+// ID, like "twitter:1234", since IDs from different sources can clash.
+func (r Report) Mapping() map[string]string {
+	mapping := map[string]string{}
+	for _, result := range r.Results {
+		if result.URL != "" {
+			mapping[result.key()] = result.URL
+		}
+	}
+	return mapping
+}
+
+// WriteCSV writes the report as CSV with the columns source, source_id,
+// source_url, url, status and message.
+func (r Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"source", "source_id", "source_url", "url", "status", "message"})
+	for _, result := range r.Results {
+		status, message := "imported", ""
+		switch {
+		case result.Err != nil:
+			status, message = "failed", result.Err.Error()
+		case result.Skipped:
+			status = "skipped"
+		case result.DryRun:
+			status = "dry-run"
+		}
+		if message == "" && len(result.Warnings) > 0 {
+			message = result.Warnings[0]
+		}
+		cw.Write([]string{result.Source, result.SourceID, result.SourceURL, result.URL, status, message})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Run publishes the items, oldest first. It stops at the first item that
+// fails and returns the report so far along with the error.
+func (im Importer) Run(ctx context.Context, items []Item) (Report, error) {
+	report := Report{}
+
+	done, err := readCheckpoint(im.Checkpoint)
+	if err != nil {
+		return report, err
+	}
+
+	sorted := make([]Item, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Entry.Published.Before(sorted[j].Entry.Published)
+	})
+
+	for _, item := range sorted {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		result := Result{
+			Source:    item.Source,
+			SourceID:  item.SourceID,
+			SourceURL: item.SourceURL,
+			Warnings:  item.Warnings,
+		}
+		switch url, ok := done[item.key()]; {
+		case ok:
+			result.URL = url
+			result.Skipped = true
+		case im.SkipReplies && item.InReplyTo != "":
+			result.Skipped = true
+		case im.DryRun:
+			result.DryRun = true
+		default:
+			post, err := im.Client.CreateEntryContext(ctx, item.Entry)
+			if err != nil {
+				result.Err = err
+				im.add(&report, result)
+				return report, err
+			}
+			result.URL = post.URL
+			done[item.key()] = post.URL
+			if err := writeCheckpoint(im.Checkpoint, done); err != nil {
+				im.add(&report, result)
+				return report, err
+			}
+		}
+		im.add(&report, result)
+	}
+	return report, nil
+}
+
+func (im Importer) add(report *Report, result Result) {
+	report.Results = append(report.Results, result)
+	if im.OnResult != nil {
+		im.OnResult(result)
+	}
+}
+
+// readCheckpoint reads the URLs of the published items by key.
+func readCheckpoint(path string) (map[string]string, error) {
+	done := map[string]string{}
+	if path == "" {
+		return done, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &done); err != nil {
+		return nil, err
+	}
+	return done, nil
+}
+
+// writeCheckpoint replaces the checkpoint file atomically.
+func writeCheckpoint(path string, done map[string]string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(done, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
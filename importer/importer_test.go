@@ -0,0 +1,216 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	micro "github.com/fiskeben/microdotblog"
+	"github.com/fiskeben/microdotblog/microblogtest"
+)
+
+func readTestdata(t *testing.T, name string, read func(f *os.File) ([]Item, error)) []Item {
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	items, err := read(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return items
+}
+
+func TestReadMastodon(t *testing.T) {
+	items := readTestdata(t, "outbox.json", func(f *os.File) ([]Item, error) { return ReadMastodon(f) })
+
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+
+	first := items[0]
+	if first.SourceID != "https://mastodon.example/users/ricco/statuses/1" || first.SourceURL != "https://mastodon.example/@ricco/1" {
+		t.Errorf("unexpected source %+v", first)
+	}
+	if !first.Entry.Published.Equal(time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected published date %v", first.Entry.Published)
+	}
+	if !reflect.DeepEqual(first.Entry.Categories, []string{"fediverse"}) {
+		t.Errorf("unexpected categories %v", first.Entry.Categories)
+	}
+	wantPhotos := []micro.MicropubPhoto{{URL: "https://files.example/2.png", Alt: "A dog"}}
+	if !reflect.DeepEqual(first.Entry.Photos, wantPhotos) {
+		t.Errorf("unexpected photos %+v", first.Entry.Photos)
+	}
+	if len(first.Warnings) != 1 || !strings.Contains(first.Warnings[0], "/media_attachments/files/1.jpg") {
+		t.Errorf("unexpected warnings %v", first.Warnings)
+	}
+
+	reply := items[1]
+	if reply.InReplyTo == "" || reply.Entry.Content != "<p>Spoilers</p><p>It was the butler</p>" {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+}
+
+func TestReadTwitter(t *testing.T) {
+	items := readTestdata(t, "tweets.js", func(f *os.File) ([]Item, error) { return ReadTwitter(f) })
+
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+
+	tweet := items[0]
+	if tweet.SourceID != "1001" || tweet.SourceURL != "https://twitter.com/i/web/status/1001" {
+		t.Errorf("unexpected source %+v", tweet)
+	}
+	if tweet.Entry.Content != "Fish & chips at https://example.com/food" {
+		t.Errorf("unexpected content %q", tweet.Entry.Content)
+	}
+	if !tweet.Entry.Published.Equal(time.Date(2018, 10, 10, 20, 19, 24, 0, time.UTC)) {
+		t.Errorf("unexpected published date %v", tweet.Entry.Published)
+	}
+	if len(tweet.Entry.Photos) != 1 || tweet.Entry.Photos[0].URL != "https://pbs.twimg.com/media/fish.jpg" {
+		t.Errorf("unexpected photos %+v", tweet.Entry.Photos)
+	}
+	if items[1].InReplyTo != "https://twitter.com/someone/status/999" {
+		t.Errorf("unexpected reply %+v", items[1])
+	}
+}
+
+func TestReadWordPress(t *testing.T) {
+	items := readTestdata(t, "wordpress.xml", func(f *os.File) ([]Item, error) { return ReadWordPress(f) })
+
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+
+	post := items[0]
+	if post.SourceID != "12" || post.SourceURL != "https://ricco.example/2017/03/first-post/" {
+		t.Errorf("unexpected source %+v", post)
+	}
+	if post.Entry.Name != "First post" || post.Entry.Content != "<p>My <strong>first</strong> post.</p>" {
+		t.Errorf("unexpected entry %+v", post.Entry)
+	}
+	if !reflect.DeepEqual(post.Entry.Categories, []string{"Coding", "go"}) {
+		t.Errorf("unexpected categories %v", post.Entry.Categories)
+	}
+	if !post.Entry.Published.Equal(time.Date(2017, 3, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected published date %v", post.Entry.Published)
+	}
+}
+
+func testItems() []Item {
+	day := func(d int) time.Time { return time.Date(2019, 1, d, 12, 0, 0, 0, time.UTC) }
+	return []Item{
+		{Source: "test", SourceID: "b", Entry: micro.MicropubEntry{Content: "Second", Published: day(2)}},
+		{Source: "test", SourceID: "a", Entry: micro.MicropubEntry{Content: "First", Published: day(1)}},
+		{Source: "test", SourceID: "c", Entry: micro.MicropubEntry{Content: "Third", Published: day(3)}},
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	s := microblogtest.NewServer()
+	defer s.Close()
+	s.AddUser("ricco", "ricco-token")
+
+	im := Importer{Client: s.APIClient("ricco-token"), DryRun: true}
+	report, err := im.Run(context.Background(), testItems())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 3 || !report.Results[0].DryRun || report.Results[0].SourceID != "a" {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if posts := s.Posts("ricco"); len(posts) != 0 {
+		t.Errorf("expected nothing to be published, got %d posts", len(posts))
+	}
+}
+
+func TestRunResumesFromCheckpoint(t *testing.T) {
+	s := microblogtest.NewServer()
+	defer s.Close()
+	s.AddUser("ricco", "ricco-token")
+
+	dir, err := ioutil.TempDir("", "importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	checkpoint := filepath.Join(dir, "checkpoint.json")
+
+	client := s.APIClient("ricco-token", micro.WithRetryPolicy(micro.RetryPolicy{MaxAttempts: 1}))
+
+	// Publish the first item, then make the second one fail.
+	failed := false
+	im := Importer{Client: client, Checkpoint: checkpoint, OnResult: func(r Result) {
+		if !failed {
+			failed = true
+			s.Fail(microblogtest.Failure{Method: "POST", Path: "/micropub", StatusCode: http.StatusInternalServerError, Times: 1})
+		}
+	}}
+	report, err := im.Run(context.Background(), testItems())
+	if err == nil {
+		t.Fatal("expected the import to fail")
+	}
+	if len(report.Results) != 2 || report.Results[0].URL == "" || report.Results[1].Err == nil {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	firstURL := report.Results[0].URL
+
+	im.OnResult = nil
+	report, err = im.Run(context.Background(), testItems())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 3 || !report.Results[0].Skipped || report.Results[0].URL != firstURL {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	posts := s.Posts("ricco")
+	if len(posts) != 3 {
+		t.Fatalf("expected 3 posts, got %d", len(posts))
+	}
+	mapping := report.Mapping()
+	if len(mapping) != 3 || mapping["test:a"] != firstURL {
+		t.Errorf("unexpected mapping %v", mapping)
+	}
+
+	var csv bytes.Buffer
+	if err := report.WriteCSV(&csv); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if len(lines) != 4 || lines[1] != "test,a,,"+firstURL+",skipped," {
+		t.Errorf("unexpected CSV:\n%s", csv.String())
+	}
+}
+
+func TestRunSkipReplies(t *testing.T) {
+	s := microblogtest.NewServer()
+	defer s.Close()
+	s.AddUser("ricco", "ricco-token")
+
+	items := testItems()
+	items[0].InReplyTo = "https://example.com/1"
+
+	im := Importer{Client: s.APIClient("ricco-token"), SkipReplies: true}
+	report, err := im.Run(context.Background(), items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Results[1].Skipped || report.Results[1].SourceID != "b" {
+		t.Errorf("expected the reply to be skipped, got %+v", report.Results[1])
+	}
+	if posts := s.Posts("ricco"); len(posts) != 2 {
+		t.Errorf("expected 2 posts, got %d", len(posts))
+	}
+}
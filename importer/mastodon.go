@@ -0,0 +1,121 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	micro "github.com/fiskeben/microdotblog"
+)
+
+// publicAudience is the ActivityPub address of public posts.
+const publicAudience = "https://www.w3.org/ns/activitystreams#Public"
+
+type mastodonOutbox struct {
+	OrderedItems []struct {
+		Type   string          `json:"type"`
+		Object json.RawMessage `json:"object"`
+	} `json:"orderedItems"`
+}
+
+type mastodonNote struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	URL       string    `json:"url"`
+	Summary   string    `json:"summary"`
+	Content   string    `json:"content"`
+	Published time.Time `json:"published"`
+	InReplyTo string    `json:"inReplyTo"`
+	To        []string  `json:"to"`
+	CC        []string  `json:"cc"`
+	Tag       []struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	} `json:"tag"`
+	Attachment []struct {
+		MediaType string `json:"mediaType"`
+		URL       string `json:"url"`
+		Name      string `json:"name"`
+	} `json:"attachment"`
+}
+
+// ReadMastodon reads the outbox.json file of a Mastodon archive.
+//
+// Boosts and posts that were not public are left out. Hashtags become
+// categories and content warnings are put in front of the content.
+// Image attachments with absolute URLs become photos. Attachments that
+// only exist as files in the archive are listed in the item's warnings.
+func ReadMastodon(r io.Reader) ([]Item, error) {
+	var outbox mastodonOutbox
+	if err := json.NewDecoder(r).Decode(&outbox); err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	for _, activity := range outbox.OrderedItems {
+		if activity.Type != "Create" {
+			continue
+		}
+		var note mastodonNote
+		// Objects that are only a URL can't be imported.
+		if err := json.Unmarshal(activity.Object, &note); err != nil || note.Type != "Note" {
+			continue
+		}
+		if !contains(note.To, publicAudience) && !contains(note.CC, publicAudience) {
+			continue
+		}
+		items = append(items, mastodonItem(note))
+	}
+	return items, nil
+}
+
+func mastodonItem(note mastodonNote) Item {
+	item := Item{
+		Source:    "mastodon",
+		SourceID:  note.ID,
+		SourceURL: note.URL,
+		InReplyTo: note.InReplyTo,
+	}
+	if item.SourceURL == "" {
+		item.SourceURL = note.ID
+	}
+
+	content := note.Content
+	if note.Summary != "" {
+		content = "<p>" + html.EscapeString(note.Summary) + "</p>" + content
+	}
+	item.Entry = micro.MicropubEntry{Content: content, Published: note.Published}
+
+	for _, tag := range note.Tag {
+		if tag.Type == "Hashtag" {
+			item.Entry.Categories = append(item.Entry.Categories, strings.TrimPrefix(tag.Name, "#"))
+		}
+	}
+	for _, a := range note.Attachment {
+		switch {
+		case !strings.HasPrefix(a.MediaType, "image/"):
+			item.Warnings = append(item.Warnings, fmt.Sprintf("attachment %s is not an image", a.URL))
+		case !isAbsoluteURL(a.URL):
+			item.Warnings = append(item.Warnings, fmt.Sprintf("attachment %s is only in the archive", a.URL))
+		default:
+			item.Entry.Photos = append(item.Entry.Photos, micro.MicropubPhoto{URL: a.URL, Alt: a.Name})
+		}
+	}
+	return item
+}
+
+func isAbsoluteURL(s string) bool {
+	return strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://")
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+window.YTD.tweets.part0 = [
+  {
+    "tweet" : {
+      "id_str" : "1001",
+      "full_text" : "Fish &amp; chips at https://t.co/abc https://t.co/img",
+      "created_at" : "Wed Oct 10 20:19:24 +0000 2018",
+      "entities" : {
+        "urls" : [ { "url" : "https://t.co/abc", "expanded_url" : "https://example.com/food" } ]
+      },
+      "extended_entities" : {
+        "media" : [
+          { "url" : "https://t.co/img", "media_url_https" : "https://pbs.twimg.com/media/fish.jpg", "type" : "photo" }
+        ]
+      }
+    }
+  },
+  {
+    "tweet" : {
+      "id_str" : "1002",
+      "full_text" : "RT @someone: not mine",
+      "created_at" : "Thu Oct 11 20:19:24 +0000 2018"
+    }
+  },
+  {
+    "tweet" : {
+      "id_str" : "1003",
+      "full_text" : "@someone agreed",
+      "created_at" : "Fri Oct 12 20:19:24 +0000 2018",
+      "in_reply_to_status_id_str" : "999",
+      "in_reply_to_screen_name" : "someone"
+    }
+  }
+]
@@ -0,0 +1,116 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"html"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	micro "github.com/fiskeben/microdotblog"
+)
+
+// twitterDate is the format of dates in Twitter archives.
+const twitterDate = "Mon Jan 02 15:04:05 -0700 2006"
+
+type twitterTweet struct {
+	IDStr             string `json:"id_str"`
+	FullText          string `json:"full_text"`
+	CreatedAt         string `json:"created_at"`
+	InReplyToStatusID string `json:"in_reply_to_status_id_str"`
+	InReplyToUser     string `json:"in_reply_to_screen_name"`
+	Entities          struct {
+		URLs []struct {
+			URL         string `json:"url"`
+			ExpandedURL string `json:"expanded_url"`
+		} `json:"urls"`
+	} `json:"entities"`
+	ExtendedEntities struct {
+		Media []struct {
+			URL           string `json:"url"`
+			MediaURLHTTPS string `json:"media_url_https"`
+			Type          string `json:"type"`
+		} `json:"media"`
+	} `json:"extended_entities"`
+}
+
+// ReadTwitter reads the tweets.js file of a Twitter archive.
+//
+// Retweets are left out. Shortened links are expanded and photos are
+// linked from Twitter's servers. Videos and GIFs are listed in the
+// item's warnings.
+func ReadTwitter(r io.Reader) ([]Item, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// The file is JavaScript that assigns the array of tweets to a
+	// variable: window.YTD.tweets.part0 = [ ... ]
+	start := strings.IndexByte(string(data), '[')
+	if start < 0 {
+		return nil, errors.New("no tweets found")
+	}
+
+	var entries []struct {
+		Tweet twitterTweet `json:"tweet"`
+	}
+	if err := json.Unmarshal(data[start:], &entries); err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	for _, entry := range entries {
+		tweet := entry.Tweet
+		if strings.HasPrefix(tweet.FullText, "RT @") {
+			continue
+		}
+		item, err := twitterItem(tweet)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func twitterItem(tweet twitterTweet) (Item, error) {
+	published, err := time.Parse(twitterDate, tweet.CreatedAt)
+	if err != nil {
+		return Item{}, err
+	}
+
+	item := Item{
+		Source:    "twitter",
+		SourceID:  tweet.IDStr,
+		SourceURL: "https://twitter.com/i/web/status/" + tweet.IDStr,
+	}
+	if tweet.InReplyToStatusID != "" {
+		item.InReplyTo = "https://twitter.com/" + tweet.InReplyToUser + "/status/" + tweet.InReplyToStatusID
+	}
+
+	// Twitter escapes &, < and > in the text.
+	text := html.UnescapeString(tweet.FullText)
+	for _, u := range tweet.Entities.URLs {
+		text = strings.Replace(text, u.URL, u.ExpandedURL, -1)
+	}
+
+	var photos []micro.MicropubPhoto
+	for _, m := range tweet.ExtendedEntities.Media {
+		text = strings.Replace(text, m.URL, "", -1)
+		if m.Type == "photo" {
+			photos = append(photos, micro.MicropubPhoto{URL: m.MediaURLHTTPS})
+		} else {
+			item.Warnings = append(item.Warnings, "media of type "+m.Type+" was not imported")
+		}
+	}
+
+	item.Entry = micro.MicropubEntry{
+		Content:   strings.TrimSpace(text),
+		Published: published,
+		Photos:    photos,
+	}
+	return item, nil
+}
@@ -0,0 +1,86 @@
+package importer
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+
+	micro "github.com/fiskeben/microdotblog"
+)
+
+type wxrItem struct {
+	Title      string `xml:"title"`
+	Link       string `xml:"link"`
+	PubDate    string `xml:"pubDate"`
+	GUID       string `xml:"guid"`
+	Content    string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PostID     string `xml:"post_id"`
+	PostDate   string `xml:"post_date_gmt"`
+	Status     string `xml:"status"`
+	PostType   string `xml:"post_type"`
+	Categories []struct {
+		Domain string `xml:"domain,attr"`
+		Name   string `xml:",chardata"`
+	} `xml:"category"`
+}
+
+// ReadWordPress reads a WordPress export (WXR) file.
+//
+// Only published posts are read, not pages, drafts or attachments.
+// Titles are kept, and both categories and tags become categories.
+func ReadWordPress(r io.Reader) ([]Item, error) {
+	var doc struct {
+		Items []wxrItem `xml:"channel>item"`
+	}
+	decoder := xml.NewDecoder(r)
+	// WXR files declare themselves as UTF-8, but some older exports
+	// use other encodings that are close enough to read as is.
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	for _, wp := range doc.Items {
+		if wp.PostType != "post" || wp.Status != "publish" {
+			continue
+		}
+		item := Item{
+			Source:    "wordpress",
+			SourceID:  wp.PostID,
+			SourceURL: wp.Link,
+			Entry: micro.MicropubEntry{
+				Name:      strings.TrimSpace(wp.Title),
+				Content:   wp.Content,
+				Published: wordPressDate(wp),
+			},
+		}
+		if item.SourceID == "" {
+			item.SourceID = wp.GUID
+		}
+		for _, c := range wp.Categories {
+			name := strings.TrimSpace(c.Name)
+			if name == "" || name == "Uncategorized" || contains(item.Entry.Categories, name) {
+				continue
+			}
+			item.Entry.Categories = append(item.Entry.Categories, name)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// wordPressDate returns the date a post was published, preferring the
+// UTC post date over the RSS date.
+func wordPressDate(wp wxrItem) time.Time {
+	if t, err := time.Parse("2006-01-02 15:04:05", wp.PostDate); err == nil {
+		return t
+	}
+	if t, err := time.Parse(time.RFC1123Z, wp.PubDate); err == nil {
+		return t
+	}
+	return time.Time{}
+}
@@ -0,0 +1,161 @@
+package microdotblog
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Attachment is a file attached to a post, like a podcast episode.
+type Attachment struct {
+	URL               string  `json:"url"`
+	MimeType          string  `json:"mime_type"`
+	Title             string  `json:"title,omitempty"`
+	SizeInBytes       int64   `json:"size_in_bytes,omitempty"`
+	DurationInSeconds float64 `json:"duration_in_seconds,omitempty"`
+}
+
+// Hub is a real-time notification endpoint for a feed.
+type Hub struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// FeedMicroblog holds the micro.blog extensions to a feed.
+type FeedMicroblog struct {
+	About          string `json:"about"`
+	ID             int64  `json:"id,string"`
+	Username       string `json:"username"`
+	Bio            string `json:"bio"`
+	IsFollowing    bool   `json:"is_following"`
+	IsYou          bool   `json:"is_you"`
+	FollowingCount int    `json:"following_count"`
+	// Extra holds the properties that are not known to this package.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *FeedMicroblog) UnmarshalJSON(data []byte) error {
+	type known FeedMicroblog
+	return unmarshalWithExtra(data, (*known)(m), &m.Extra)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m FeedMicroblog) MarshalJSON() ([]byte, error) {
+	type known FeedMicroblog
+	return marshalWithExtra(known(m), m.Extra)
+}
+
+// PostMicroblog holds the micro.blog extensions to a post.
+type PostMicroblog struct {
+	IsDeletable  bool   `json:"is_deletable"`
+	IsFavorite   bool   `json:"is_favourite"`
+	IsBookmark   bool   `json:"is_bookmark"`
+	DateRelative string `json:"date_relative"`
+	// InReplyToID and InReplyToURL identify the post this is a
+	// reply to, when the server includes them.
+	InReplyToID  int64  `json:"in_reply_to_id,string,omitempty"`
+	InReplyToURL string `json:"in_reply_to_url,omitempty"`
+	// Extra holds the properties that are not known to this package.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *PostMicroblog) UnmarshalJSON(data []byte) error {
+	type known PostMicroblog
+	return unmarshalWithExtra(data, (*known)(m), &m.Extra)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m PostMicroblog) MarshalJSON() ([]byte, error) {
+	type known PostMicroblog
+	return marshalWithExtra(known(m), m.Extra)
+}
+
+// AuthorMicroblog holds the micro.blog extensions to an author.
+type AuthorMicroblog struct {
+	Username    string `json:"username"`
+	IsFollowing bool   `json:"is_following"`
+	// Extra holds the properties that are not known to this package.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *AuthorMicroblog) UnmarshalJSON(data []byte) error {
+	type known AuthorMicroblog
+	return unmarshalWithExtra(data, (*known)(m), &m.Extra)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m AuthorMicroblog) MarshalJSON() ([]byte, error) {
+	type known AuthorMicroblog
+	return marshalWithExtra(known(m), m.Extra)
+}
+
+// unmarshalWithExtra decodes data into the struct pointed to by v and
+// puts the properties that don't match one of its fields in extra.
+func unmarshalWithExtra(data []byte, v interface{}, extra *map[string]json.RawMessage) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for _, key := range jsonKeys(reflect.TypeOf(v).Elem()) {
+		delete(all, key)
+	}
+	if len(all) == 0 {
+		all = nil
+	}
+	*extra = all
+	return nil
+}
+
+// marshalWithExtra encodes v and adds the properties in extra that
+// don't clash with its fields.
+func marshalWithExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	known := jsonKeys(reflect.TypeOf(v))
+	for key, value := range extra {
+		if !contains(known, key) {
+			all[key] = value
+		}
+	}
+	return json.Marshal(all)
+}
+
+// jsonKeys returns the JSON names of the fields of a struct type.
+func jsonKeys(t reflect.Type) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" || f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		keys = append(keys, name)
+	}
+	return keys
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package microdotblog
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const jsonFeed11 = `
+{
+	"version": "https://jsonfeed.org/version/1.1",
+	"title": "Ricco's blog",
+	"home_page_url": "https://ricco.example/",
+	"feed_url": "https://ricco.example/feed.json",
+	"description": "Stuff",
+	"next_url": "https://ricco.example/feed.json?page=2",
+	"icon": "https://ricco.example/icon.png",
+	"favicon": "https://ricco.example/favicon.ico",
+	"language": "da",
+	"hubs": [{"type": "WebSub", "url": "https://hub.example/"}],
+	"authors": [{"name": "Ricco", "url": "https://ricco.example/", "avatar": "https://ricco.example/me.jpg"}],
+	"_microblog": {"username": "ricco", "is_you": true, "theme": "dark"},
+	"items": [
+		{
+			"id": "42",
+			"url": "https://ricco.example/2020/01/01/hello.html",
+			"external_url": "https://example.com/",
+			"title": "Hello",
+			"content_html": "<p>Hello</p>",
+			"content_text": "Hello",
+			"summary": "A greeting",
+			"image": "https://ricco.example/hello.jpg",
+			"banner_image": "https://ricco.example/banner.jpg",
+			"date_published": "2020-01-01T12:00:00Z",
+			"date_modified": "2020-01-02T12:00:00Z",
+			"authors": [{"name": "Ricco", "_microblog": {"username": "ricco", "pronouns": "he/him"}}],
+			"tags": ["greeting"],
+			"language": "en",
+			"attachments": [
+				{"url": "https://ricco.example/hello.mp3", "mime_type": "audio/mpeg", "title": "Hello", "size_in_bytes": 1024, "duration_in_seconds": 3.5}
+			],
+			"_microblog": {"is_favourite": true, "date_relative": "today", "is_linkpost": false, "reactions": {"like": 3}}
+		}
+	]
+}`
+
+func TestJSONFeed11(t *testing.T) {
+	var feed Feed
+	if err := json.Unmarshal([]byte(jsonFeed11), &feed); err != nil {
+		t.Fatal(err)
+	}
+
+	if feed.Description != "Stuff" || feed.NextURL != "https://ricco.example/feed.json?page=2" ||
+		feed.Icon == "" || feed.Favicon == "" || feed.Language != "da" {
+		t.Errorf("unexpected feed fields %+v", feed)
+	}
+	if len(feed.Hubs) != 1 || feed.Hubs[0].Type != "WebSub" {
+		t.Errorf("unexpected hubs %+v", feed.Hubs)
+	}
+	if len(feed.Authors) != 1 || feed.Authors[0].Avatar != "https://ricco.example/me.jpg" {
+		t.Errorf("unexpected authors %+v", feed.Authors)
+	}
+	if !feed.MicroblogProperties.IsYou || string(feed.MicroblogProperties.Extra["theme"]) != `"dark"` {
+		t.Errorf("unexpected feed _microblog %+v", feed.MicroblogProperties)
+	}
+
+	post := feed.Items[0]
+	modified := time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC)
+	if post.Title != "Hello" || post.ContentText != "Hello" || post.Summary != "A greeting" ||
+		post.ExternalURL != "https://example.com/" || post.Image == "" || post.BannerImage == "" ||
+		post.Language != "en" || post.DateModified == nil || !post.DateModified.Equal(modified) {
+		t.Errorf("unexpected post fields %+v", post)
+	}
+	wantAttachment := Attachment{
+		URL:               "https://ricco.example/hello.mp3",
+		MimeType:          "audio/mpeg",
+		Title:             "Hello",
+		SizeInBytes:       1024,
+		DurationInSeconds: 3.5,
+	}
+	if len(post.Attachments) != 1 || post.Attachments[0] != wantAttachment {
+		t.Errorf("unexpected attachments %+v", post.Attachments)
+	}
+	if !reflect.DeepEqual(post.Tags, TagList{"greeting"}) {
+		t.Errorf("unexpected tags %v", post.Tags)
+	}
+	if string(post.Authors[0].MicroblogProperties.Extra["pronouns"]) != `"he/him"` {
+		t.Errorf("unexpected author _microblog %+v", post.Authors[0].MicroblogProperties)
+	}
+
+	props := post.MicroblogProperties
+	if !props.IsFavorite || props.DateRelative != "today" {
+		t.Errorf("unexpected post _microblog %+v", props)
+	}
+	wantExtra := map[string]json.RawMessage{
+		"is_linkpost": json.RawMessage(`false`),
+		"reactions":   json.RawMessage(`{"like": 3}`),
+	}
+	if !reflect.DeepEqual(props.Extra, wantExtra) {
+		t.Errorf("expected extra %s, got %s", wantExtra, props.Extra)
+	}
+}
+
+func TestMicroblogPropertiesRoundTrip(t *testing.T) {
+	var props PostMicroblog
+	if err := json.Unmarshal([]byte(`{"is_bookmark": true, "new_field": [1, 2]}`), &props); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(props)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["is_bookmark"] != true {
+		t.Errorf("expected is_bookmark to be kept in %s", data)
+	}
+	if !reflect.DeepEqual(decoded["new_field"], []interface{}{1.0, 2.0}) {
+		t.Errorf("expected new_field to be kept in %s", data)
+	}
+}
+
+func TestMicroblogPropertiesKnownFieldsWin(t *testing.T) {
+	props := AuthorMicroblog{
+		Username: "ricco",
+		Extra:    map[string]json.RawMessage{"username": json.RawMessage(`"someone"`)},
+	}
+	data, err := json.Marshal(props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded AuthorMicroblog
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Username != "ricco" || decoded.Extra != nil {
+		t.Errorf("unexpected round trip %+v from %s", decoded, data)
+	}
+}
@@ -0,0 +1,799 @@
+// Package microblogtest provides an in-memory stand-in for micro.blog
+// that a real APIClient can be pointed at in tests.
+//
+//	server := microblogtest.NewServer()
+//	defer server.Close()
+//
+//	server.AddUser("manton", "manton-token")
+//	server.AddPost("manton", "Hello world")
+//	server.AddUser("ricco", "ricco-token")
+//
+//	client := server.APIClient("ricco-token")
+//	client.Follow("manton")
+//	feed, err := client.GetUserPosts("manton")
+package microblogtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	micro "github.com/fiskeben/microdotblog"
+)
+
+// DefaultPageSize is the number of posts returned by feeds
+// when no count is given.
+const DefaultPageSize = 20
+
+// Failure is a scripted error response. See Server.Fail.
+type Failure struct {
+	// Method and Path select the requests that fail.
+	// An empty Method matches all methods.
+	Method string
+	Path   string
+	// StatusCode and Body make up the response.
+	StatusCode int
+	Body       string
+	// Times is the number of requests that fail before the failure is
+	// removed. Zero means that all matching requests fail.
+	Times int
+}
+
+// Server is an in-memory micro.blog. It keeps users, posts, follows,
+// favourites, bookmarks and Micropub state, checks the Authorization
+// header of every request and answers like micro.blog does.
+type Server struct {
+	*httptest.Server
+
+	// CheckSeconds is returned from /posts/check.
+	CheckSeconds int
+
+	mu       sync.Mutex
+	users    map[string]*user
+	tokens   map[string]*user
+	posts    []*post
+	uploads  map[string]upload
+	failures []*Failure
+	nextID   int64
+}
+
+type user struct {
+	username   string
+	following  map[string]bool
+	favourites map[int64]bool
+}
+
+type post struct {
+	id         int64
+	author     string
+	content    string
+	name       string
+	categories []string
+	photos     []string
+	bookmarkOf string
+	inReplyTo  int64
+	published  time.Time
+	deleted    bool
+}
+
+type upload struct {
+	contentType string
+	data        []byte
+}
+
+// NewServer starts a new server. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		users:   map[string]*user{},
+		tokens:  map[string]*user{},
+		uploads: map[string]upload{},
+		nextID:  1,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// APIClient creates a client that talks to the server with the given token.
+func (s *Server) APIClient(token string, opts ...micro.Option) micro.APIClient {
+	opts = append([]micro.Option{
+		micro.WithBaseURL(s.URL),
+		micro.WithHTTPClient(s.Client()),
+	}, opts...)
+	return micro.NewAPIClient(token, opts...)
+}
+
+// AddUser adds a user that authenticates with token.
+func (s *Server) AddUser(username, token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := &user{
+		username:   username,
+		following:  map[string]bool{},
+		favourites: map[int64]bool{},
+	}
+	s.users[username] = u
+	s.tokens[token] = u
+}
+
+// AddPost adds a post by the user and returns its ID.
+func (s *Server) AddPost(username, content string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addPost(&post{author: username, content: content, published: time.Now()}).id
+}
+
+// Fail makes requests matching f fail with the status code and body of f.
+func (s *Server) Fail(f Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = append(s.failures, &f)
+}
+
+// Following lists the usernames the user follows, sorted.
+func (s *Server) Following(username string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		return nil
+	}
+	return sortedKeys(u.following)
+}
+
+// Posts returns the posts written by the user, newest first.
+func (s *Server) Posts(username string) []micro.Post {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []micro.Post
+	for _, p := range s.timeline(func(p *post) bool { return p.author == username && p.bookmarkOf == "" }) {
+		items = append(items, s.item(p, nil))
+	}
+	return items
+}
+
+func (s *Server) addPost(p *post) *post {
+	p.id = s.nextID
+	s.nextID++
+	s.posts = append(s.posts, p)
+	return p
+}
+
+func (s *Server) postURL(p *post) string {
+	return fmt.Sprintf("%s/%s/%d", s.URL, p.author, p.id)
+}
+
+func (s *Server) findPost(id int64) *post {
+	for _, p := range s.posts {
+		if p.id == id && !p.deleted {
+			return p
+		}
+	}
+	return nil
+}
+
+func (s *Server) findPostByURL(postURL string) *post {
+	for _, p := range s.posts {
+		if s.postURL(p) == postURL {
+			return p
+		}
+	}
+	return nil
+}
+
+// timeline returns the posts that match, newest first.
+func (s *Server) timeline(match func(p *post) bool) []*post {
+	var posts []*post
+	for i := len(s.posts) - 1; i >= 0; i-- {
+		if p := s.posts[i]; !p.deleted && match(p) {
+			posts = append(posts, p)
+		}
+	}
+	return posts
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.scriptedFailure(w, r) {
+		return
+	}
+
+	if r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/uploads/") {
+		s.serveUpload(w, r)
+		return
+	}
+
+	viewer := s.authenticate(r)
+	if viewer == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "A valid token is required.")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	route := r.Method + " " + parts[0]
+	if len(parts) > 1 {
+		route += "/" + parts[1]
+	}
+
+	switch {
+	case route == "GET posts/all":
+		s.writeFeed(w, r, viewer, "Timeline", func(p *post) bool {
+			return p.bookmarkOf == "" && (p.author == viewer.username || viewer.following[p.author])
+		})
+	case route == "GET posts/mentions":
+		s.writeFeed(w, r, viewer, "Mentions", func(p *post) bool {
+			return p.bookmarkOf == "" && strings.Contains(p.content, "@"+viewer.username)
+		})
+	case route == "GET posts/favorites":
+		s.writeFeed(w, r, viewer, "Favorites", func(p *post) bool {
+			return viewer.favourites[p.id]
+		})
+	case route == "POST posts/favorites":
+		s.favourite(w, viewer, r.URL.Query().Get("id"), true)
+	case route == "DELETE posts/favorites" && len(parts) == 3:
+		s.favourite(w, viewer, parts[2], false)
+	case route == "GET posts/discover":
+		s.writeFeed(w, r, viewer, "Discover", func(p *post) bool {
+			return p.bookmarkOf == "" && p.inReplyTo == 0
+		})
+	case route == "GET posts/check":
+		s.check(w, r, viewer)
+	case route == "GET posts/conversation":
+		s.conversation(w, r, viewer)
+	case route == "POST posts/reply":
+		s.reply(w, r, viewer)
+	case route == "GET posts/bookmarks" && len(parts) == 2:
+		tag := r.URL.Query().Get("tag")
+		s.writeFeed(w, r, viewer, "Bookmarks", func(p *post) bool {
+			return p.bookmarkOf != "" && p.author == viewer.username && (tag == "" || contains(p.categories, tag))
+		})
+	case route == "GET posts/bookmarks" && len(parts) == 3 && parts[2] == "tags":
+		s.bookmarkTags(w, viewer)
+	case route == "DELETE posts/bookmarks" && len(parts) == 3:
+		s.deletePost(w, viewer, parts[2], true)
+	case strings.HasPrefix(route, "DELETE posts/") && len(parts) == 2:
+		s.deletePost(w, viewer, parts[1], false)
+	case strings.HasPrefix(route, "GET posts/") && len(parts) == 2:
+		s.userPosts(w, r, viewer, parts[1])
+	case route == "POST users/follow":
+		s.follow(w, viewer, r.URL.Query().Get("username"), true)
+	case route == "POST users/unfollow":
+		s.follow(w, viewer, r.URL.Query().Get("username"), false)
+	case route == "GET users/following" && len(parts) == 3:
+		s.following(w, viewer, parts[2])
+	case route == "GET micropub":
+		s.micropubQuery(w, r, viewer)
+	case route == "POST micropub" && len(parts) == 1:
+		s.micropub(w, r, viewer)
+	case route == "POST micropub/media":
+		s.media(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "not_found", "Page not found.")
+	}
+}
+
+func (s *Server) scriptedFailure(w http.ResponseWriter, r *http.Request) bool {
+	for i, f := range s.failures {
+		if (f.Method != "" && f.Method != r.Method) || f.Path != r.URL.Path {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			}
+		}
+		w.WriteHeader(f.StatusCode)
+		w.Write([]byte(f.Body))
+		return true
+	}
+	return false
+}
+
+func (s *Server) authenticate(r *http.Request) *user {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		return nil
+	}
+	return s.tokens[token]
+}
+
+func writeError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, map[string]string{"error": code, "error_description": description})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// page applies count, before_id and since_id to posts sorted newest first.
+func page(posts []*post, query url.Values) []*post {
+	beforeID, _ := strconv.ParseInt(query.Get("before_id"), 10, 64)
+	sinceID, _ := strconv.ParseInt(query.Get("since_id"), 10, 64)
+	count, err := strconv.Atoi(query.Get("count"))
+	if err != nil || count <= 0 {
+		count = DefaultPageSize
+	}
+
+	var result []*post
+	for _, p := range posts {
+		if (beforeID > 0 && p.id >= beforeID) || p.id <= sinceID {
+			continue
+		}
+		if len(result) == count {
+			break
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+func (s *Server) item(p *post, viewer *user) micro.Post {
+	item := micro.Post{
+		ID:            p.id,
+		URL:           s.postURL(p),
+		ContentHTML:   p.content,
+		DatePublished: p.published,
+		Author:        s.author(p.author, viewer),
+	}
+	if p.bookmarkOf != "" {
+		item.URL = p.bookmarkOf
+		item.ContentHTML = fmt.Sprintf(`<p><a href="%s">%s</a></p>`, html.EscapeString(p.bookmarkOf), html.EscapeString(p.bookmarkOf))
+		item.Tags = p.categories
+		item.MicroblogProperties.IsBookmark = true
+	}
+	if parent := s.findPost(p.inReplyTo); parent != nil {
+		item.MicroblogProperties.InReplyToID = parent.id
+		item.MicroblogProperties.InReplyToURL = s.postURL(parent)
+	}
+	if viewer != nil {
+		item.MicroblogProperties.IsDeletable = p.author == viewer.username
+		item.MicroblogProperties.IsFavorite = viewer.favourites[p.id]
+	}
+	item.MicroblogProperties.DateRelative = p.published.Format("3:04 pm")
+	return item
+}
+
+func (s *Server) author(username string, viewer *user) micro.Author {
+	author := micro.Author{
+		Name:   username,
+		URL:    fmt.Sprintf("%s/%s/", s.URL, username),
+		Avatar: fmt.Sprintf("%s/%s/avatar.jpg", s.URL, username),
+	}
+	author.MicroblogProperties.Username = username
+	if viewer != nil {
+		author.MicroblogProperties.IsFollowing = viewer.following[username]
+	}
+	return author
+}
+
+func (s *Server) feed(title string, posts []*post, viewer *user) micro.Feed {
+	feed := micro.Feed{
+		Version:     "https://jsonfeed.org/version/1",
+		Title:       "Micro.blog - " + title,
+		HomepageURL: s.URL + "/",
+		Items:       []micro.Post{},
+	}
+	for _, p := range posts {
+		feed.Items = append(feed.Items, s.item(p, viewer))
+	}
+	return feed
+}
+
+func (s *Server) writeFeed(w http.ResponseWriter, r *http.Request, viewer *user, title string, match func(p *post) bool) {
+	feed := s.feed(title, page(s.timeline(match), r.URL.Query()), viewer)
+	feed.FeedURL = s.URL + r.URL.Path
+	writeJSON(w, http.StatusOK, feed)
+}
+
+func (s *Server) userPosts(w http.ResponseWriter, r *http.Request, viewer *user, username string) {
+	u, ok := s.users[username]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "User not found.")
+		return
+	}
+
+	posts := s.timeline(func(p *post) bool { return p.author == username && p.bookmarkOf == "" })
+	feed := s.feed(username, page(posts, r.URL.Query()), viewer)
+	feed.FeedURL = s.URL + r.URL.Path
+	feed.Author = s.author(username, viewer)
+	feed.MicroblogProperties.Username = username
+	feed.MicroblogProperties.IsFollowing = viewer.following[username]
+	feed.MicroblogProperties.IsYou = viewer == u
+	feed.MicroblogProperties.FollowingCount = len(u.following)
+	writeJSON(w, http.StatusOK, feed)
+}
+
+func (s *Server) check(w http.ResponseWriter, r *http.Request, viewer *user) {
+	sinceID, _ := strconv.ParseInt(r.URL.Query().Get("since_id"), 10, 64)
+	posts := s.timeline(func(p *post) bool {
+		return p.id > sinceID && p.bookmarkOf == "" && (p.author == viewer.username || viewer.following[p.author])
+	})
+	writeJSON(w, http.StatusOK, micro.Check{Count: len(posts), CheckSeconds: s.CheckSeconds})
+}
+
+func (s *Server) conversation(w http.ResponseWriter, r *http.Request, viewer *user) {
+	id, _ := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	p := s.findPost(id)
+	if p == nil {
+		writeError(w, http.StatusNotFound, "not_found", "Post not found.")
+		return
+	}
+
+	root := s.root(p)
+	posts := s.timeline(func(p *post) bool { return s.root(p) == root })
+	writeJSON(w, http.StatusOK, s.feed("Conversation", posts, viewer))
+}
+
+func (s *Server) root(p *post) *post {
+	for p.inReplyTo != 0 {
+		parent := s.findPost(p.inReplyTo)
+		if parent == nil {
+			break
+		}
+		p = parent
+	}
+	return p
+}
+
+func (s *Server) reply(w http.ResponseWriter, r *http.Request, viewer *user) {
+	id, _ := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	parent := s.findPost(id)
+	if parent == nil {
+		writeError(w, http.StatusNotFound, "not_found", "Post not found.")
+		return
+	}
+
+	p := s.addPost(&post{
+		author:    viewer.username,
+		content:   "<p>" + html.EscapeString(r.FormValue("text")) + "</p>",
+		inReplyTo: parent.id,
+		published: time.Now(),
+	})
+	w.Header().Set("Location", s.postURL(p))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) favourite(w http.ResponseWriter, viewer *user, rawID string, favourite bool) {
+	id, _ := strconv.ParseInt(rawID, 10, 64)
+	if s.findPost(id) == nil {
+		writeError(w, http.StatusNotFound, "not_found", "Post not found.")
+		return
+	}
+	if favourite {
+		viewer.favourites[id] = true
+	} else {
+		delete(viewer.favourites, id)
+	}
+	writeJSON(w, http.StatusOK, map[string]string{})
+}
+
+func (s *Server) deletePost(w http.ResponseWriter, viewer *user, rawID string, bookmark bool) {
+	id, _ := strconv.ParseInt(rawID, 10, 64)
+	p := s.findPost(id)
+	if p == nil || (p.bookmarkOf != "") != bookmark {
+		writeError(w, http.StatusNotFound, "not_found", "Post not found.")
+		return
+	}
+	if p.author != viewer.username {
+		writeError(w, http.StatusForbidden, "forbidden", "You can only delete your own posts.")
+		return
+	}
+	p.deleted = true
+	writeJSON(w, http.StatusOK, map[string]string{})
+}
+
+func (s *Server) bookmarkTags(w http.ResponseWriter, viewer *user) {
+	tags := map[string]bool{}
+	for _, p := range s.timeline(func(p *post) bool { return p.bookmarkOf != "" && p.author == viewer.username }) {
+		for _, tag := range p.categories {
+			tags[tag] = true
+		}
+	}
+	writeJSON(w, http.StatusOK, sortedKeys(tags))
+}
+
+func (s *Server) follow(w http.ResponseWriter, viewer *user, username string, follow bool) {
+	if _, ok := s.users[username]; !ok {
+		writeError(w, http.StatusNotFound, "not_found", "User not found.")
+		return
+	}
+	if follow {
+		viewer.following[username] = true
+	} else {
+		delete(viewer.following, username)
+	}
+	writeJSON(w, http.StatusOK, map[string]string{})
+}
+
+func (s *Server) following(w http.ResponseWriter, viewer *user, username string) {
+	u, ok := s.users[username]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "User not found.")
+		return
+	}
+
+	users := []micro.User{}
+	for _, name := range sortedKeys(u.following) {
+		users = append(users, micro.User{
+			Name:        name,
+			Username:    name,
+			URL:         fmt.Sprintf("%s/%s/", s.URL, name),
+			Avatar:      fmt.Sprintf("%s/%s/avatar.jpg", s.URL, name),
+			IsFollowing: viewer.following[name],
+			IsYou:       name == viewer.username,
+		})
+	}
+	writeJSON(w, http.StatusOK, users)
+}
+
+func (s *Server) micropubQuery(w http.ResponseWriter, r *http.Request, viewer *user) {
+	switch r.URL.Query().Get("q") {
+	case "config":
+		writeJSON(w, http.StatusOK, micro.MicropubConfig{
+			MediaEndpoint: s.URL + "/micropub/media",
+			Destinations: []micro.MicropubDestination{
+				{UID: fmt.Sprintf("%s/%s/", s.URL, viewer.username), Name: viewer.username},
+			},
+			PostTypes: []micro.MicropubPostType{
+				{Type: "note", Name: "Post"},
+				{Type: "photo", Name: "Photo"},
+			},
+			SyndicateTo: []micro.SyndicationTarget{},
+		})
+	case "syndicate-to":
+		writeJSON(w, http.StatusOK, map[string][]micro.SyndicationTarget{"syndicate-to": {}})
+	case "category":
+		categories := map[string]bool{}
+		for _, p := range s.timeline(func(p *post) bool { return p.author == viewer.username && p.bookmarkOf == "" }) {
+			for _, category := range p.categories {
+				categories[category] = true
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string][]string{"categories": sortedKeys(categories)})
+	case "source":
+		p := s.findPostByURL(r.URL.Query().Get("url"))
+		if p == nil || p.deleted {
+			writeError(w, http.StatusNotFound, "not_found", "Post not found.")
+			return
+		}
+		writeJSON(w, http.StatusOK, s.source(p))
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_request", "Unknown query.")
+	}
+}
+
+func (s *Server) source(p *post) micro.MicropubSource {
+	props := map[string][]interface{}{
+		"content":   {p.content},
+		"published": {p.published.Format(time.RFC3339)},
+		"url":       {s.postURL(p)},
+		"uid":       {strconv.FormatInt(p.id, 10)},
+	}
+	if p.name != "" {
+		props["name"] = []interface{}{p.name}
+	}
+	for _, category := range p.categories {
+		props["category"] = append(props["category"], category)
+	}
+	for _, photo := range p.photos {
+		props["photo"] = append(props["photo"], photo)
+	}
+	return micro.MicropubSource{Type: []string{"h-entry"}, Properties: props}
+}
+
+// micropubRequest is a Micropub request decoded from either a form or JSON.
+type micropubRequest struct {
+	Action     string                   `json:"action"`
+	URL        string                   `json:"url"`
+	Type       []string                 `json:"type"`
+	Properties map[string][]interface{} `json:"properties"`
+	Replace    map[string][]interface{} `json:"replace"`
+	Add        map[string][]interface{} `json:"add"`
+	Delete     interface{}              `json:"delete"`
+}
+
+func (s *Server) micropub(w http.ResponseWriter, r *http.Request, viewer *user) {
+	req := micropubRequest{}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON.")
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "Invalid form.")
+			return
+		}
+		req.Action = r.PostForm.Get("action")
+		req.URL = r.PostForm.Get("url")
+		req.Properties = map[string][]interface{}{}
+		for key, values := range r.PostForm {
+			key = strings.TrimSuffix(key, "[]")
+			for _, value := range values {
+				req.Properties[key] = append(req.Properties[key], value)
+			}
+		}
+	}
+
+	switch req.Action {
+	case "":
+		s.create(w, viewer, req.Properties)
+	case "update", "delete", "undelete":
+		p := s.findPostByURL(req.URL)
+		if p == nil {
+			writeError(w, http.StatusNotFound, "not_found", "Post not found.")
+			return
+		}
+		if p.author != viewer.username {
+			writeError(w, http.StatusForbidden, "forbidden", "You can only change your own posts.")
+			return
+		}
+		switch req.Action {
+		case "update":
+			s.update(p, req)
+		case "delete":
+			p.deleted = true
+		case "undelete":
+			p.deleted = false
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_request", "Unknown action.")
+	}
+}
+
+func (s *Server) create(w http.ResponseWriter, viewer *user, props map[string][]interface{}) {
+	p := &post{
+		author:     viewer.username,
+		content:    firstString(props["content"]),
+		name:       firstString(props["name"]),
+		categories: stringValues(props["category"]),
+		bookmarkOf: firstString(props["bookmark-of"]),
+		published:  time.Now(),
+	}
+	for _, photo := range props["photo"] {
+		if m, ok := photo.(map[string]interface{}); ok {
+			photo = m["value"]
+		}
+		if photoURL, ok := photo.(string); ok {
+			p.photos = append(p.photos, photoURL)
+		}
+	}
+	if published, err := time.Parse(time.RFC3339, firstString(props["published"])); err == nil {
+		p.published = published
+	}
+	if p.content == "" && p.bookmarkOf == "" && len(p.photos) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Content is required.")
+		return
+	}
+
+	s.addPost(p)
+	w.Header().Set("Location", s.postURL(p))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) update(p *post, req micropubRequest) {
+	for property, values := range req.Replace {
+		switch property {
+		case "content":
+			p.content = firstString(values)
+		case "name":
+			p.name = firstString(values)
+		case "category":
+			p.categories = stringValues(values)
+		}
+	}
+	for property, values := range req.Add {
+		if property == "category" {
+			p.categories = append(p.categories, stringValues(values)...)
+		}
+	}
+	switch del := req.Delete.(type) {
+	case []interface{}:
+		for _, property := range stringValues(del) {
+			switch property {
+			case "name":
+				p.name = ""
+			case "category":
+				p.categories = nil
+			}
+		}
+	case map[string]interface{}:
+		if values, ok := del["category"].([]interface{}); ok {
+			remove := stringValues(values)
+			var kept []string
+			for _, category := range p.categories {
+				if !contains(remove, category) {
+					kept = append(kept, category)
+				}
+			}
+			p.categories = kept
+		}
+	}
+}
+
+func (s *Server) media(w http.ResponseWriter, r *http.Request) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "A file is required.")
+		return
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Could not read the file.")
+		return
+	}
+
+	path := fmt.Sprintf("/uploads/%d/%s", len(s.uploads)+1, url.PathEscape(header.Filename))
+	s.uploads[path] = upload{contentType: header.Header.Get("Content-Type"), data: data}
+	w.Header().Set("Location", s.URL+path)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) serveUpload(w http.ResponseWriter, r *http.Request) {
+	u, ok := s.uploads[r.URL.EscapedPath()]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "File not found.")
+		return
+	}
+	w.Header().Set("Content-Type", u.contentType)
+	w.Write(u.data)
+}
+
+func firstString(values []interface{}) string {
+	if len(values) == 0 {
+		return ""
+	}
+	s, _ := values[0].(string)
+	return s
+}
+
+func stringValues(values []interface{}) []string {
+	var result []string
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
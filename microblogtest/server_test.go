@@ -0,0 +1,224 @@
+package microblogtest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	micro "github.com/fiskeben/microdotblog"
+)
+
+func newTestServer() *Server {
+	s := NewServer()
+	s.AddUser("ricco", "ricco-token")
+	s.AddUser("manton", "manton-token")
+	s.AddPost("manton", "<p>Hello from Manton</p>")
+	s.AddPost("manton", "<p>Hi @ricco</p>")
+	return s
+}
+
+func TestFollowAndGetUserPosts(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	c := s.APIClient("ricco-token")
+
+	feed, err := c.GetUserPosts("manton")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Items) != 2 || feed.MicroblogProperties.IsFollowing {
+		t.Errorf("Unexpected feed %+v", feed)
+	}
+
+	if err = c.Follow("manton"); err != nil {
+		t.Fatal(err)
+	}
+	if following := s.Following("ricco"); !reflect.DeepEqual(following, []string{"manton"}) {
+		t.Errorf("Expected ricco to follow manton, got %v", following)
+	}
+
+	feed, err = c.GetUserPosts("manton")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !feed.MicroblogProperties.IsFollowing || !feed.Items[0].Author.MicroblogProperties.IsFollowing {
+		t.Error("Expected the feed to show that ricco follows manton")
+	}
+
+	timeline, err := c.GetPosts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timeline.Items) != 2 {
+		t.Errorf("Expected manton's posts in the timeline, got %d posts", len(timeline.Items))
+	}
+
+	users, err := c.Followers("ricco")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].Username != "manton" || !users[0].IsFollowing {
+		t.Errorf("Unexpected users %+v", users)
+	}
+
+	if err = c.Unfollow("manton"); err != nil {
+		t.Fatal(err)
+	}
+	if following := s.Following("ricco"); len(following) != 0 {
+		t.Errorf("Expected ricco to follow nobody, got %v", following)
+	}
+}
+
+func TestPostReplyAndConversation(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	ricco := s.APIClient("ricco-token")
+	manton := s.APIClient("manton-token", micro.WithCreatedPostLookup())
+
+	post, err := manton.Post("<p>Anyone there?</p>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.ID == 0 || post.URL == "" {
+		t.Fatalf("Expected the created post, got %+v", post)
+	}
+
+	reply, err := ricco.Reply(post.ID, "@manton yes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.URL == "" {
+		t.Error("Expected the reply to have a URL")
+	}
+
+	conversation, err := ricco.GetConversation(post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conversation.Items) != 2 {
+		t.Errorf("Expected 2 posts in the conversation, got %d", len(conversation.Items))
+	}
+	thread := micro.NewThread(conversation)
+	if thread.Root == nil || thread.Root.Post.ID != post.ID || len(thread.Root.Children) != 1 {
+		t.Errorf("Expected the reply below the post, got %+v", thread.Root)
+	}
+
+	mentions, err := ricco.GetMentions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mentions.Items) != 1 {
+		t.Errorf("Expected 1 mention, got %d", len(mentions.Items))
+	}
+}
+
+func TestMicropub(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	c := s.APIClient("ricco-token")
+
+	photo := micro.NewPhotoFromBytes([]byte("GIF89a"), "cat.gif", "")
+	post, err := c.PostPhoto("My cat", photo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := c.Source(post.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source.Value("content") != "My cat" || source.Value("photo") == "" {
+		t.Errorf("Unexpected source %+v", source)
+	}
+
+	err = c.UpdateEntry(post.URL, micro.ReplaceProperty("content", "My cat, Felix"), micro.AddToProperty("category", "cats"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	categories, err := c.Categories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(categories, []string{"cats"}) {
+		t.Errorf("Unexpected categories %v", categories)
+	}
+
+	if err = c.DeleteEntry(post.URL); err != nil {
+		t.Fatal(err)
+	}
+	if posts := s.Posts("ricco"); len(posts) != 0 {
+		t.Errorf("Expected the post to be deleted, got %v", posts)
+	}
+
+	if err = c.UndeleteEntry(post.URL); err != nil {
+		t.Fatal(err)
+	}
+	if posts := s.Posts("ricco"); len(posts) != 1 || posts[0].ContentHTML != "My cat, Felix" {
+		t.Errorf("Expected the updated post to be restored, got %v", posts)
+	}
+}
+
+func TestFavouritesAndDelete(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	c := s.APIClient("ricco-token")
+	post, err := c.Post("<p>Delete me</p>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeline, err := c.GetPosts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := timeline.Items[0].ID
+
+	if err = c.Favourite(id); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Unfavourite(id); err != nil {
+		t.Fatal(err)
+	}
+	favourites, err := c.GetFavourites()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(favourites.Items) != 0 {
+		t.Errorf("Expected no favourites, got %d", len(favourites.Items))
+	}
+
+	if err = c.DeletePost(id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Source(post.URL); !errors.Is(err, micro.ErrNotFound) {
+		t.Errorf("Expected the post to be deleted, got %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	_, err := s.APIClient("wrong-token").GetPosts()
+	var apiErr *micro.APIError
+	if !errors.Is(err, micro.ErrUnauthorized) || !errors.As(err, &apiErr) || apiErr.ErrorCode != "unauthorized" {
+		t.Errorf("Expected 401 for an invalid token, got %v", err)
+	}
+
+	c := s.APIClient("ricco-token")
+	if _, err = c.GetUserPosts("nobody"); !errors.Is(err, micro.ErrNotFound) {
+		t.Errorf("Expected 404 for an unknown user, got %v", err)
+	}
+
+	s.Fail(Failure{Method: "GET", Path: "/posts/all", StatusCode: 503, Body: "Down for maintenance", Times: 1})
+	if _, err = c.GetPosts(); !errors.Is(err, micro.ErrServerError) {
+		t.Errorf("Expected scripted failure, got %v", err)
+	}
+	if _, err = c.GetPosts(); err != nil {
+		t.Errorf("Expected the failure to happen only once, got %v", err)
+	}
+}
@@ -0,0 +1,307 @@
+package microdotblog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// MicropubEncoding selects how an entry is sent to the Micropub endpoint.
+type MicropubEncoding int
+
+const (
+	// FormEncoded sends the entry as application/x-www-form-urlencoded.
+	FormEncoded MicropubEncoding = iota
+	// JSONEncoded sends the entry as application/json.
+	JSONEncoded
+)
+
+// MicropubPhoto is a photo that has already been uploaded,
+// along with its alternative text.
+type MicropubPhoto struct {
+	URL string
+	Alt string
+}
+
+// MicropubEntry is a new post to be created with CreateEntry.
+// Empty fields are left out of the request.
+type MicropubEntry struct {
+	// Content is the text of the post.
+	Content string
+	// Name is the title of the post.
+	Name string
+	// Categories are the categories, or tags, of the post.
+	Categories []string
+	// SyndicateTo lists the UIDs of the syndication targets to cross-post to.
+	SyndicateTo []string
+	// Published overrides the publishing date of the post.
+	Published time.Time
+	// Photos are attached to the post.
+	Photos []MicropubPhoto
+	// BookmarkOf is the URL of the web page the entry bookmarks.
+	BookmarkOf string
+	// Destination is the UID of the blog to post to
+	// when the account has more than one.
+	Destination string
+	// Encoding selects form or JSON encoding. Defaults to FormEncoded.
+	Encoding MicropubEncoding
+}
+
+// contentType returns the content type that matches the entry's encoding.
+func (e MicropubEntry) contentType() string {
+	if e.Encoding == JSONEncoded {
+		return "application/json"
+	}
+	return "application/x-www-form-urlencoded"
+}
+
+// encode returns the request body for the entry.
+func (e MicropubEntry) encode() ([]byte, error) {
+	if e.Encoding == JSONEncoded {
+		return json.Marshal(e.jsonEntry())
+	}
+	return []byte(e.form().Encode()), nil
+}
+
+func (e MicropubEntry) form() url.Values {
+	data := url.Values{}
+	data.Set("h", "entry")
+	if e.Content != "" {
+		data.Set("content", e.Content)
+	}
+	if e.Name != "" {
+		data.Set("name", e.Name)
+	}
+	for _, category := range e.Categories {
+		data.Add("category[]", category)
+	}
+	for _, target := range e.SyndicateTo {
+		data.Add("mp-syndicate-to[]", target)
+	}
+	if !e.Published.IsZero() {
+		data.Set("published", e.Published.Format(time.RFC3339))
+	}
+	for _, photo := range e.Photos {
+		data.Add("photo[]", photo.URL)
+		data.Add("mp-photo-alt[]", photo.Alt)
+	}
+	if e.BookmarkOf != "" {
+		data.Set("bookmark-of", e.BookmarkOf)
+	}
+	if e.Destination != "" {
+		data.Set("mp-destination", e.Destination)
+	}
+	return data
+}
+
+type jsonEntry struct {
+	Type       []string                 `json:"type"`
+	Properties map[string][]interface{} `json:"properties"`
+}
+
+func (e MicropubEntry) jsonEntry() jsonEntry {
+	props := map[string][]interface{}{}
+	if e.Content != "" {
+		props["content"] = []interface{}{e.Content}
+	}
+	if e.Name != "" {
+		props["name"] = []interface{}{e.Name}
+	}
+	for _, category := range e.Categories {
+		props["category"] = append(props["category"], category)
+	}
+	for _, target := range e.SyndicateTo {
+		props["mp-syndicate-to"] = append(props["mp-syndicate-to"], target)
+	}
+	if !e.Published.IsZero() {
+		props["published"] = []interface{}{e.Published.Format(time.RFC3339)}
+	}
+	for _, photo := range e.Photos {
+		if photo.Alt == "" {
+			props["photo"] = append(props["photo"], photo.URL)
+			continue
+		}
+		props["photo"] = append(props["photo"], map[string]string{"value": photo.URL, "alt": photo.Alt})
+	}
+	if e.BookmarkOf != "" {
+		props["bookmark-of"] = []interface{}{e.BookmarkOf}
+	}
+	if e.Destination != "" {
+		props["mp-destination"] = []interface{}{e.Destination}
+	}
+	return jsonEntry{Type: []string{"h-entry"}, Properties: props}
+}
+
+// UpdateAction is the kind of change an UpdateOperation makes.
+type UpdateAction string
+
+const (
+	// UpdateReplace replaces all values of a property.
+	UpdateReplace UpdateAction = "replace"
+	// UpdateAdd adds values to a property.
+	UpdateAdd UpdateAction = "add"
+	// UpdateDelete removes values from a property,
+	// or the whole property if no values are given.
+	UpdateDelete UpdateAction = "delete"
+)
+
+// UpdateOperation is a single change to a property of an existing entry.
+// Use ReplaceProperty, AddToProperty, DeleteFromProperty and DeleteProperty
+// to create one.
+type UpdateOperation struct {
+	Action   UpdateAction
+	Property string
+	Values   []interface{}
+}
+
+// ReplaceProperty replaces all values of the property with values.
+func ReplaceProperty(property string, values ...interface{}) UpdateOperation {
+	return UpdateOperation{Action: UpdateReplace, Property: property, Values: values}
+}
+
+// AddToProperty adds values to the property.
+func AddToProperty(property string, values ...interface{}) UpdateOperation {
+	return UpdateOperation{Action: UpdateAdd, Property: property, Values: values}
+}
+
+// DeleteFromProperty removes the given values from the property.
+func DeleteFromProperty(property string, values ...interface{}) UpdateOperation {
+	return UpdateOperation{Action: UpdateDelete, Property: property, Values: values}
+}
+
+// DeleteProperty removes the property altogether.
+func DeleteProperty(property string) UpdateOperation {
+	return UpdateOperation{Action: UpdateDelete, Property: property}
+}
+
+// updateRequest builds the JSON body of a Micropub update of the entry at
+// postURL. Micropub cannot mix removing whole properties with removing
+// single values in the same request.
+func updateRequest(postURL string, ops []UpdateOperation) (map[string]interface{}, error) {
+	if len(ops) == 0 {
+		return nil, errors.New("no update operations given")
+	}
+
+	replace := map[string][]interface{}{}
+	add := map[string][]interface{}{}
+	deleteValues := map[string][]interface{}{}
+	deleteProperties := []string{}
+
+	for _, op := range ops {
+		switch op.Action {
+		case UpdateReplace:
+			replace[op.Property] = append(replace[op.Property], op.Values...)
+		case UpdateAdd:
+			add[op.Property] = append(add[op.Property], op.Values...)
+		case UpdateDelete:
+			if len(op.Values) == 0 {
+				deleteProperties = append(deleteProperties, op.Property)
+			} else {
+				deleteValues[op.Property] = append(deleteValues[op.Property], op.Values...)
+			}
+		default:
+			return nil, fmt.Errorf("unknown update action '%s'", op.Action)
+		}
+	}
+
+	if len(deleteValues) > 0 && len(deleteProperties) > 0 {
+		return nil, errors.New("cannot delete whole properties and single values in the same update")
+	}
+
+	body := map[string]interface{}{
+		"action": "update",
+		"url":    postURL,
+	}
+	if len(replace) > 0 {
+		body["replace"] = replace
+	}
+	if len(add) > 0 {
+		body["add"] = add
+	}
+	if len(deleteValues) > 0 {
+		body["delete"] = deleteValues
+	}
+	if len(deleteProperties) > 0 {
+		body["delete"] = deleteProperties
+	}
+	return body, nil
+}
+
+// MicropubConfig describes what a Micropub server supports.
+// It is returned by MicropubConfig.
+type MicropubConfig struct {
+	// MediaEndpoint is where photos and other files are uploaded.
+	MediaEndpoint string `json:"media-endpoint"`
+	// Destinations are the blogs the account can post to.
+	Destinations []MicropubDestination `json:"destination"`
+	// PostTypes are the kinds of posts the server supports.
+	PostTypes []MicropubPostType `json:"post-types"`
+	// SyndicateTo are the targets posts can be cross-posted to.
+	SyndicateTo []SyndicationTarget `json:"syndicate-to"`
+}
+
+// MicropubDestination is a blog that can be posted to.
+// Pass its UID as MicropubEntry.Destination.
+type MicropubDestination struct {
+	UID  string `json:"uid"`
+	Name string `json:"name"`
+}
+
+// MicropubPostType is a kind of post, e.g. a note or a photo.
+type MicropubPostType struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// SyndicationTarget is a service posts can be cross-posted to.
+// Pass its UID in MicropubEntry.SyndicateTo.
+type SyndicationTarget struct {
+	UID  string `json:"uid"`
+	Name string `json:"name"`
+}
+
+// MicropubSource is the source of a post as returned by a Micropub
+// q=source query.
+type MicropubSource struct {
+	Type       []string                 `json:"type"`
+	Properties map[string][]interface{} `json:"properties"`
+}
+
+// Value returns the first value of the property as a string.
+// HTML content is returned as {"html": "..."} and is unwrapped.
+func (s MicropubSource) Value(property string) string {
+	values := s.Properties[property]
+	if len(values) == 0 {
+		return ""
+	}
+	switch v := values[0].(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		if html, ok := v["html"].(string); ok {
+			return html
+		}
+		if value, ok := v["value"].(string); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// Post maps the source to a Post.
+func (s MicropubSource) Post() *Post {
+	post := &Post{
+		URL:         s.Value("url"),
+		ContentHTML: s.Value("content"),
+	}
+	if id, err := strconv.ParseInt(s.Value("uid"), 10, 64); err == nil {
+		post.ID = id
+	}
+	if published, err := time.Parse(time.RFC3339, s.Value("published")); err == nil {
+		post.DatePublished = published
+	}
+	return post
+}
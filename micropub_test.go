@@ -0,0 +1,331 @@
+package microdotblog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testEntry = MicropubEntry{
+	Content:     "Hello world",
+	Name:        "A title",
+	Categories:  []string{"go", "micropub"},
+	SyndicateTo: []string{"https://twitter.com/ricco"},
+	Published:   time.Date(2018, 1, 2, 15, 4, 5, 0, time.UTC),
+	Photos:      []MicropubPhoto{{URL: "https://example.org/cat.jpg", Alt: "A cat"}},
+	Destination: "https://ricco.micro.blog/",
+}
+
+func TestCreateEntryForm(t *testing.T) {
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		if contentType := r.Header.Get("Content-Type"); contentType != "application/x-www-form-urlencoded" {
+			t.Errorf("Expected form encoding, got '%s'", contentType)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := map[string][]string{
+			"h":                 {"entry"},
+			"content":           {"Hello world"},
+			"name":              {"A title"},
+			"category[]":        {"go", "micropub"},
+			"mp-syndicate-to[]": {"https://twitter.com/ricco"},
+			"published":         {"2018-01-02T15:04:05Z"},
+			"photo[]":           {"https://example.org/cat.jpg"},
+			"mp-photo-alt[]":    {"A cat"},
+			"mp-destination":    {"https://ricco.micro.blog/"},
+		}
+		for key, values := range expected {
+			if !reflect.DeepEqual(r.PostForm[key], values) {
+				t.Errorf("Expected %s to be %v, got %v", key, values, r.PostForm[key])
+			}
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	if _, err := c.CreateEntry(testEntry); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCreateEntryJSON(t *testing.T) {
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		if contentType := r.Header.Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("Expected JSON encoding, got '%s'", contentType)
+		}
+
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var entry struct {
+			Type       []string                 `json:"type"`
+			Properties map[string][]interface{} `json:"properties"`
+		}
+		if err = json.Unmarshal(data, &entry); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(entry.Type, []string{"h-entry"}) {
+			t.Errorf("Expected type h-entry, got %v", entry.Type)
+		}
+		if !reflect.DeepEqual(entry.Properties["category"], []interface{}{"go", "micropub"}) {
+			t.Errorf("Unexpected categories %v", entry.Properties["category"])
+		}
+		photo, _ := entry.Properties["photo"][0].(map[string]interface{})
+		if photo["value"] != "https://example.org/cat.jpg" || photo["alt"] != "A cat" {
+			t.Errorf("Unexpected photo %v", entry.Properties["photo"])
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	entry := testEntry
+	entry.Encoding = JSONEncoded
+	if _, err := c.CreateEntry(entry); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCreatedPostLocation(t *testing.T) {
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "https://ricco.micro.blog/2018/01/02/hello.html")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	post, err := c.Post("Hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.URL != "https://ricco.micro.blog/2018/01/02/hello.html" {
+		t.Errorf("Expected URL from Location header, got '%s'", post.URL)
+	}
+}
+
+func TestCreatedPostLookup(t *testing.T) {
+	location := "https://ricco.micro.blog/2018/01/02/hello.html"
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			w.Header().Set("Location", location)
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		if r.URL.Query().Get("q") != "source" || r.URL.Query().Get("url") != location {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{
+			"type": ["h-entry"],
+			"properties": {
+				"content": ["Hello"],
+				"published": ["2018-01-02T15:04:05+00:00"],
+				"uid": ["12345"],
+				"url": ["https://ricco.micro.blog/2018/01/02/hello.html"]
+			}
+		}`))
+	}
+	c := apiClient{
+		httpClient:    aClient{httpClient: handlerClient{handler: handler}, token: "ABCD12345"},
+		lookupCreated: true,
+	}
+
+	post, err := c.Reply(1234, "Hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.ID != 12345 || post.URL != location || post.ContentHTML != "Hello" {
+		t.Errorf("Unexpected post %+v", post)
+	}
+	if post.DatePublished.IsZero() {
+		t.Error("Expected a publishing date")
+	}
+}
+
+func TestCreatedPostLookupFailure(t *testing.T) {
+	location := "https://ricco.micro.blog/2018/01/02/hello.html"
+	posts := 0
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			posts++
+			w.Header().Set("Location", location)
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	c := apiClient{
+		httpClient:    aClient{httpClient: handlerClient{handler: handler}, token: "ABCD12345"},
+		lookupCreated: true,
+	}
+
+	post, err := c.Post("Hello")
+	if err != nil {
+		t.Fatalf("Expected the created post despite the failed lookup, got %v", err)
+	}
+	if post.URL != location {
+		t.Errorf("Expected URL from Location header, got '%s'", post.URL)
+	}
+	if posts != 1 {
+		t.Errorf("Expected the post to be sent once, got %d", posts)
+	}
+}
+
+func TestUpdateEntry(t *testing.T) {
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		if contentType := r.Header.Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("Expected JSON encoding, got '%s'", contentType)
+		}
+
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := map[string]interface{}{
+			"action":  "update",
+			"url":     "https://ricco.micro.blog/2018/01/02/hello.html",
+			"replace": map[string]interface{}{"content": []interface{}{"Hello again"}},
+			"add":     map[string]interface{}{"category": []interface{}{"go"}},
+			"delete":  map[string]interface{}{"category": []interface{}{"php"}},
+		}
+		if !reflect.DeepEqual(body, expected) {
+			t.Errorf("Expected %v, got %v", expected, body)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := c.UpdateEntry("https://ricco.micro.blog/2018/01/02/hello.html",
+		ReplaceProperty("content", "Hello again"),
+		AddToProperty("category", "go"),
+		DeleteFromProperty("category", "php"),
+	)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpdateEntryRejectsMixedDeletes(t *testing.T) {
+	c := makeMockClient("ABCD12345", "")
+	err := c.UpdateEntry("https://ricco.micro.blog/2018/01/02/hello.html",
+		DeleteProperty("name"),
+		DeleteFromProperty("category", "php"),
+	)
+	if err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestDeleteEntry(t *testing.T) {
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body["action"] != "delete" || body["url"] != "https://ricco.micro.blog/2018/01/02/hello.html" {
+			t.Errorf("Unexpected request %v", body)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := c.DeleteEntry("https://ricco.micro.blog/2018/01/02/hello.html"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUndeleteEntryNotAuthorized(t *testing.T) {
+	c := makeFailingMockClient(401, "Not authorized")
+	err := c.UndeleteEntry("https://ricco.micro.blog/2018/01/02/hello.html")
+	if _, ok := err.(NotAuthorized); !ok {
+		t.Errorf("Expected HTTP 401 not authorized, got %v", err)
+	}
+}
+
+func TestMicropubConfig(t *testing.T) {
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		if q := r.URL.Query().Get("q"); q != "config" {
+			t.Errorf("Expected q=config, got q=%s", q)
+		}
+		w.Write([]byte(`{
+			"media-endpoint": "https://micro.blog/micropub/media",
+			"destination": [
+				{"uid": "https://ricco.micro.blog/", "name": "ricco.micro.blog"},
+				{"uid": "https://test.micro.blog/", "name": "test.micro.blog"}
+			],
+			"post-types": [{"type": "note", "name": "Post"}, {"type": "photo", "name": "Photo"}],
+			"syndicate-to": [{"uid": "https://twitter.com/ricco", "name": "Twitter"}]
+		}`))
+	})
+
+	config, err := c.MicropubConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.MediaEndpoint != "https://micro.blog/micropub/media" {
+		t.Errorf("Unexpected media endpoint '%s'", config.MediaEndpoint)
+	}
+	if len(config.Destinations) != 2 || config.Destinations[1].UID != "https://test.micro.blog/" {
+		t.Errorf("Unexpected destinations %v", config.Destinations)
+	}
+	if len(config.PostTypes) != 2 || config.PostTypes[1].Type != "photo" {
+		t.Errorf("Unexpected post types %v", config.PostTypes)
+	}
+	if len(config.SyndicateTo) != 1 || config.SyndicateTo[0].Name != "Twitter" {
+		t.Errorf("Unexpected syndication targets %v", config.SyndicateTo)
+	}
+}
+
+func TestSource(t *testing.T) {
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("q") != "source" || query.Get("url") != "https://ricco.micro.blog/2018/01/02/hello.html" {
+			t.Errorf("Unexpected query %v", query)
+		}
+		if !reflect.DeepEqual(query["properties[]"], []string{"content", "category"}) {
+			t.Errorf("Unexpected properties %v", query["properties[]"])
+		}
+		w.Write([]byte(`{"properties": {"content": [{"html": "<p>Hello</p>"}], "category": ["go"]}}`))
+	})
+
+	source, err := c.Source("https://ricco.micro.blog/2018/01/02/hello.html", "content", "category")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content := source.Value("content"); content != "<p>Hello</p>" {
+		t.Errorf("Unexpected content '%s'", content)
+	}
+	if category := source.Value("category"); category != "go" {
+		t.Errorf("Unexpected category '%s'", category)
+	}
+}
+
+func TestSyndicateTargetsAndCategories(t *testing.T) {
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("q") {
+		case "syndicate-to":
+			w.Write([]byte(`{"syndicate-to": [{"uid": "https://twitter.com/ricco", "name": "Twitter"}]}`))
+		case "category":
+			w.Write([]byte(`{"categories": ["go", "beer"]}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+
+	targets, err := c.SyndicateTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 1 || targets[0].UID != "https://twitter.com/ricco" {
+		t.Errorf("Unexpected syndication targets %v", targets)
+	}
+
+	categories, err := c.Categories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(categories, []string{"go", "beer"}) {
+		t.Errorf("Unexpected categories %v", categories)
+	}
+}
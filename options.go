@@ -0,0 +1,87 @@
+package microdotblog
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Option configures the client returned by NewAPIClient.
+type Option func(*apiClient)
+
+// WithHTTPClient makes the client send its requests through c
+// instead of http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(a *apiClient) {
+		a.httpClient.httpClient = c
+	}
+}
+
+// WithBaseURL points the client at another server than https://micro.blog,
+// e.g. a self-hosted Micropub server or a local stand-in for tests.
+func WithBaseURL(baseURL string) Option {
+	return func(a *apiClient) {
+		a.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(a *apiClient) {
+		a.httpClient.userAgent = userAgent
+	}
+}
+
+// WithTimeout limits how long a single request, including reading
+// the response, may take. When requests are retried, every attempt
+// gets the full timeout, and the delays between attempts don't count.
+func WithTimeout(timeout time.Duration) Option {
+	return func(a *apiClient) {
+		a.httpClient.timeout = timeout
+	}
+}
+
+// WithCreatedPostLookup makes Post, Reply and the other methods that create
+// posts look up the created post through Micropub (q=source), so that the
+// returned Post has its content, ID and publishing date and not only its URL.
+// If the lookup fails, the post was still created and the returned Post
+// only has its URL.
+func WithCreatedPostLookup() Option {
+	return func(a *apiClient) {
+		a.lookupCreated = true
+	}
+}
+
+// WithRetryPolicy makes the client retry failed requests according to p.
+// See DefaultRetryPolicy for a sensible default.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(a *apiClient) {
+		a.httpClient.retry = p
+	}
+}
+
+// WithRateLimiters limits how often the client sends requests.
+// Reads (GET requests) wait for the read limiter and everything else for
+// the write limiter. Either may be nil to leave it unlimited, and the same
+// limiter may be passed to several clients to share the limit between them.
+func WithRateLimiters(read, write *RateLimiter) Option {
+	return func(a *apiClient) {
+		a.httpClient.readLimit = read
+		a.httpClient.writeLimit = write
+	}
+}
+
+// WithCache stores the responses to GET requests in c. Responses with an
+// ETag or Last-Modified header are revalidated with a conditional request
+// and served from the cache when the server responds 304 Not Modified.
+// Responses without either header are served from the cache without a
+// request until they are older than ttl. A ttl of 0 only caches responses
+// that can be revalidated.
+//
+// Use NewMemoryCache or NewDiskCache, or your own implementation of Cache.
+func WithCache(c Cache, ttl time.Duration) Option {
+	return func(a *apiClient) {
+		a.httpClient.cache = c
+		a.httpClient.cacheTTL = ttl
+	}
+}
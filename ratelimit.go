@@ -0,0 +1,181 @@
+package microdotblog
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket that limits how often requests are sent.
+// It is safe to share between goroutines and between clients.
+//
+// The limiter adapts to the server: it halves its rate when the server
+// responds with 429 Too Many Requests, pauses for as long as the
+// Retry-After or X-RateLimit-Reset headers ask it to, and slowly recovers
+// its configured rate again after successful requests.
+type RateLimiter struct {
+	mu          sync.Mutex
+	baseRate    float64
+	rate        float64
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// minRateFactor is how far below its configured rate a limiter may slow down.
+const minRateFactor = 16
+
+// NewRateLimiter creates a limiter that allows perSecond requests per second
+// on average and bursts of up to burst requests.
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		baseRate: perSecond,
+		rate:     perSecond,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// Wait blocks until a request may be sent or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	for {
+		l.mu.Lock()
+		delay := l.reserve(time.Now())
+		l.mu.Unlock()
+
+		if delay <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a token if one is available and otherwise returns
+// how long to wait before trying again.
+func (l *RateLimiter) reserve(now time.Time) time.Duration {
+	l.refill(now)
+
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	if l.rate <= 0 {
+		return time.Second
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+func (l *RateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(l.last).Seconds()
+	l.last = now
+	if elapsed <= 0 {
+		return
+	}
+	l.tokens += elapsed * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+// Rate returns the current number of requests allowed per second.
+// A nil limiter doesn't limit requests and returns +Inf.
+func (l *RateLimiter) Rate() float64 {
+	if l == nil {
+		return math.Inf(1)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// observe adapts the limiter to the rate limit information in res.
+func (l *RateLimiter) observe(res *http.Response) {
+	if l == nil || res == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.refill(now)
+
+	if res.StatusCode == http.StatusTooManyRequests {
+		l.rate /= 2
+		if floor := l.baseRate / minRateFactor; l.rate < floor {
+			l.rate = floor
+		}
+		l.tokens = 0
+		if delay, ok := retryAfter(res.Header.Get("Retry-After")); ok {
+			l.pause(now.Add(delay))
+		}
+		return
+	}
+
+	remaining, err := strconv.Atoi(res.Header.Get("X-RateLimit-Remaining"))
+	if err == nil {
+		reset, ok := rateLimitReset(now, res.Header.Get("X-RateLimit-Reset"))
+		if remaining <= 0 && ok {
+			l.pause(reset)
+			return
+		}
+		if ok && reset.After(now) {
+			rate := float64(remaining) / reset.Sub(now).Seconds()
+			if rate > l.baseRate {
+				rate = l.baseRate
+			}
+			l.rate = rate
+			return
+		}
+	}
+
+	// Recover a tenth of the configured rate with every successful request.
+	if res.StatusCode < 300 && l.rate < l.baseRate {
+		l.rate += l.baseRate / 10
+		if l.rate > l.baseRate {
+			l.rate = l.baseRate
+		}
+	}
+}
+
+func (l *RateLimiter) pause(until time.Time) {
+	if until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// rateLimitReset parses an X-RateLimit-Reset header, which servers send
+// either as a Unix timestamp or as a number of seconds from now.
+func rateLimitReset(now time.Time, value string) (time.Time, bool) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return time.Time{}, false
+	}
+	if n > 1000000000 {
+		return time.Unix(n, 0), true
+	}
+	return now.Add(time.Duration(n) * time.Second), true
+}
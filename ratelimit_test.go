@@ -0,0 +1,113 @@
+package microdotblog
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBurst(t *testing.T) {
+	l := NewRateLimiter(50, 2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Two requests are allowed right away, the next two take 20ms each.
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected requests to be limited, took %v", elapsed)
+	}
+}
+
+func TestRateLimiterContext(t *testing.T) {
+	l := NewRateLimiter(0.001, 1)
+	l.Wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestRateLimiterAdapts(t *testing.T) {
+	l := NewRateLimiter(10, 1)
+
+	l.observe(&http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}})
+	if rate := l.Rate(); rate != 5 {
+		t.Errorf("Expected rate to be halved, got %v", rate)
+	}
+
+	for i := 0; i < 10; i++ {
+		l.observe(&http.Response{StatusCode: http.StatusOK, Header: http.Header{}})
+	}
+	if rate := l.Rate(); rate != 10 {
+		t.Errorf("Expected rate to recover, got %v", rate)
+	}
+
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	l.observe(&http.Response{StatusCode: http.StatusOK, Header: http.Header{
+		"X-Ratelimit-Remaining": {"0"},
+		"X-Ratelimit-Reset":     {reset},
+	}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err == nil {
+		t.Error("Expected the limiter to pause until the rate limit resets")
+	}
+}
+
+func TestClientSharesRateLimiter(t *testing.T) {
+	write := NewRateLimiter(1000, 1)
+	read := NewRateLimiter(0.001, 1)
+
+	c := apiClient{
+		httpClient: aClient{
+			httpClient: handlerClient{handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(posts))
+			}},
+			readLimit:  read,
+			writeLimit: write,
+		},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Favourite(1234); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := c.GetPosts(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetPostsContext(ctx); err == nil {
+		t.Error("Expected the second read to wait for the read limiter")
+	}
+}
+
+func TestNilRateLimiter(t *testing.T) {
+	var l *RateLimiter
+	if err := l.Wait(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if rate := l.Rate(); !math.IsInf(rate, 1) {
+		t.Errorf("Expected an unlimited rate, got %v", rate)
+	}
+}
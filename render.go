@@ -0,0 +1,326 @@
+package microdotblog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PlainText returns the content of the post as plain text. Paragraphs are
+// separated by blank lines, links are followed by their URL, images are
+// replaced by their alternative text, quotes are prefixed with "> " and
+// list items with "- " or their number. Entities are decoded.
+func (p Post) PlainText() string {
+	return renderHTML(p.ContentHTML, false)
+}
+
+// Markdown returns the content of the post as Markdown.
+// @-mentions are kept as plain @username.
+func (p Post) Markdown() string {
+	return renderHTML(p.ContentHTML, true)
+}
+
+// indent is used in place of spaces that must survive the whitespace
+// cleanup, like the indentation of list items and the content of <pre>.
+const indent = "\x01"
+
+// verbatim starts the lines of <pre> blocks, which must be left as they are.
+const verbatim = "\x02"
+
+var (
+	spaceRun        = regexp.MustCompile(`[ \t\r\f]+`)
+	spaceAroundLine = regexp.MustCompile(` *\n *`)
+	blankLines      = regexp.MustCompile(`\n{3,}`)
+	htmlSpecial     = strings.NewReplacer(`&`, `&amp;`, `<`, `&lt;`, `>`, `&gt;`)
+	markdownSpecial = strings.NewReplacer(
+		`\`, `\\`, `*`, `\*`, `_`, `\_`, "`", "\\`", `[`, `\[`, `]`, `\]`,
+		`&`, `&amp;`, `<`, `&lt;`, `>`, `&gt;`,
+	)
+	// urlSpecial percent-encodes the characters that would end a link
+	// destination or an autolink early. & is left alone so that query
+	// strings keep working.
+	urlSpecial = strings.NewReplacer(
+		" ", "%20", "\t", "%09", "\n", "%0A", "\r", "%0D",
+		"(", "%28", ")", "%29", "<", "%3C", ">", "%3E", `\`, "%5C",
+	)
+	// urlScheme matches the start of the absolute URLs Markdown
+	// turns into autolinks.
+	urlScheme = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]{1,31}:`)
+	// blockStart matches text at the start of a line that Markdown would
+	// read as a heading, list item or rule. The first group holds the
+	// markers of the quotes and lists the line is in, whose spaces are
+	// still indent at this point.
+	blockStart = regexp.MustCompile(`^((?:>\x01?|-\x01|\d+\.\x01|\x01)*)(#{1,6}(?: |$)|[-+](?: |$)|\d+[.)](?: |$)|[-=]{3,}$)`)
+)
+
+func renderHTML(content string, markdown bool) string {
+	r := htmlRenderer{markdown: markdown}
+	s := cleanup(r.children(parseHTML(content)))
+	if markdown {
+		s = escapeBlockStarts(s)
+	}
+	s = strings.Replace(s, verbatim, "", -1)
+	return strings.Replace(s, indent, " ", -1)
+}
+
+// escapeBlockStarts escapes the text at the start of lines that would
+// otherwise turn into Markdown syntax, like "# not a heading".
+func escapeBlockStarts(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		m := blockStart.FindStringSubmatchIndex(line)
+		if m == nil {
+			continue
+		}
+		start, end := m[4], m[5]
+		at := start
+		if c := line[start]; c >= '0' && c <= '9' {
+			// Escape the punctuation after the number: 1\. not a list
+			at = start + strings.IndexAny(line[start:end], ".)")
+		}
+		lines[i] = line[:at] + `\` + line[at:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fence returns a run of backticks longer than any in s, and at least min long.
+func fence(s string, min int) string {
+	longest, run := 0, 0
+	for _, c := range s {
+		if c == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest+1 > min {
+		min = longest + 1
+	}
+	return strings.Repeat("`", min)
+}
+
+// cleanup collapses whitespace and removes blank lines at the
+// beginning and end, leaving at most one blank line between blocks.
+func cleanup(s string) string {
+	s = spaceRun.ReplaceAllString(s, " ")
+	s = spaceAroundLine.ReplaceAllString(s, "\n")
+	s = blankLines.ReplaceAllString(s, "\n\n")
+	return strings.Trim(s, " \n")
+}
+
+// prefixLines puts first in front of the first line of s
+// and rest in front of the others.
+func prefixLines(s, first, rest string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			lines[i] = first + line
+		case line == "":
+			lines[i] = strings.TrimRight(rest, indent)
+		default:
+			lines[i] = rest + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+type htmlRenderer struct {
+	markdown bool
+}
+
+func (r htmlRenderer) children(n *htmlNode) string {
+	var b strings.Builder
+	for _, child := range n.children {
+		b.WriteString(r.node(child))
+	}
+	return b.String()
+}
+
+func (r htmlRenderer) block(s string) string {
+	return "\n\n" + s + "\n\n"
+}
+
+func (r htmlRenderer) node(n *htmlNode) string {
+	if n.tag == "" {
+		text := strings.Replace(n.text, "\n", " ", -1)
+		if r.markdown {
+			text = markdownSpecial.Replace(text)
+		}
+		return text
+	}
+
+	switch n.tag {
+	case "script", "style", "head", "title":
+		return ""
+	case "br":
+		if r.markdown {
+			return indent + indent + "\n"
+		}
+		return "\n"
+	case "hr":
+		if r.markdown {
+			return r.block("---")
+		}
+		return r.block("")
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		text := cleanup(r.children(n))
+		if r.markdown {
+			level := int(n.tag[1] - '0')
+			text = strings.Repeat("#", level) + indent + text
+		}
+		return r.block(text)
+	case "blockquote":
+		return r.block(prefixLines(cleanup(r.children(n)), ">"+indent, ">"+indent))
+	case "ul", "ol":
+		return r.block(r.list(n))
+	case "li":
+		return r.block(r.listItem(n, "-"+indent))
+	case "pre":
+		text := strings.Trim(textContent(n), "\n")
+		if r.markdown {
+			marker := fence(text, 3)
+			text = marker + "\n" + text + "\n" + marker
+		}
+		text = strings.Replace(text, " ", indent, -1)
+		return r.block(verbatim + strings.Replace(text, "\n", "\n"+verbatim, -1))
+	case "code":
+		text := textContent(n)
+		if r.markdown {
+			marker := fence(text, 1)
+			if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+				text = indent + text + indent
+			}
+			return marker + text + marker
+		}
+		return text
+	case "strong", "b":
+		if r.markdown {
+			return emphasis(r.children(n), "**")
+		}
+	case "em", "i":
+		if r.markdown {
+			return emphasis(r.children(n), "*")
+		}
+	case "a":
+		return r.link(n)
+	case "img":
+		return r.image(n)
+	}
+
+	if blockElements[n.tag] {
+		return r.block(r.children(n))
+	}
+	return r.children(n)
+}
+
+func emphasis(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	return marker + trimmed + marker
+}
+
+func (r htmlRenderer) list(n *htmlNode) string {
+	var items []string
+	number := 1
+	for _, child := range n.children {
+		if child.tag != "li" {
+			if text := cleanup(r.node(child)); text != "" {
+				items = append(items, text)
+			}
+			continue
+		}
+		marker := "-" + indent
+		if n.tag == "ol" {
+			marker = fmt.Sprintf("%d.%s", number, indent)
+			number++
+		}
+		items = append(items, r.listItem(child, marker))
+	}
+	return strings.Join(items, "\n")
+}
+
+func (r htmlRenderer) listItem(n *htmlNode, marker string) string {
+	text := cleanup(r.children(n))
+	// Keep nested lists directly below the item instead of
+	// separated by a blank line.
+	text = strings.Replace(text, "\n\n", "\n", -1)
+	return prefixLines(text, marker, strings.Repeat(indent, len(strings.Replace(marker, indent, " ", -1))))
+}
+
+func (r htmlRenderer) link(n *htmlNode) string {
+	href := n.attrs["href"]
+	if mention := strings.TrimSpace(textContent(n)); isMention(mention) {
+		if r.markdown {
+			return markdownSpecial.Replace(mention)
+		}
+		return mention
+	}
+
+	text := strings.TrimSpace(cleanup(r.children(n)))
+	if href == "" {
+		return text
+	}
+	if r.markdown {
+		if (text == "" || text == markdownSpecial.Replace(href)) && urlScheme.MatchString(href) {
+			return "<" + urlSpecial.Replace(href) + ">"
+		}
+		if text == "" {
+			text = markdownSpecial.Replace(href)
+		}
+		return "[" + text + "](" + destination(href) + ")"
+	}
+	if text == "" {
+		return href
+	}
+	if text == href || strings.TrimPrefix(strings.TrimPrefix(href, "https://"), "http://") == text {
+		return text
+	}
+	return text + " (" + href + ")"
+}
+
+func (r htmlRenderer) image(n *htmlNode) string {
+	src := n.attrs["src"]
+	alt := strings.TrimSpace(n.attrs["alt"])
+	if r.markdown {
+		return "![" + markdownSpecial.Replace(alt) + "](" + destination(src) + ")"
+	}
+	if alt != "" {
+		return "[Image: " + alt + "]"
+	}
+	if src != "" {
+		return "[Image: " + src + "]"
+	}
+	return ""
+}
+
+// destination returns url as the destination of a Markdown link or image.
+// Entities are decoded in destinations, so & is escaped as one.
+func destination(url string) string {
+	return strings.Replace(urlSpecial.Replace(url), "&", "&amp;", -1)
+}
+
+// isMention reports whether the text of a link is an @-mention.
+func isMention(text string) bool {
+	if !strings.HasPrefix(text, "@") || len(text) < 2 {
+		return false
+	}
+	return !strings.ContainsAny(text, " \n")
+}
+
+// textContent returns all text inside n without any markup.
+func textContent(n *htmlNode) string {
+	if n.tag == "" {
+		return n.text
+	}
+	var b strings.Builder
+	for _, child := range n.children {
+		b.WriteString(textContent(child))
+	}
+	return b.String()
+}
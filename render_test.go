@@ -0,0 +1,149 @@
+package microdotblog
+
+import "testing"
+
+var renderTests = []struct {
+	name      string
+	html      string
+	plainText string
+	markdown  string
+}{
+	{
+		name:      "paragraphs",
+		html:      "<p>Hello\nworld.</p><p>Second   paragraph.</p>",
+		plainText: "Hello world.\n\nSecond paragraph.",
+		markdown:  "Hello world.\n\nSecond paragraph.",
+	},
+	{
+		name:      "unwrapped text",
+		html:      "Just a short note.",
+		plainText: "Just a short note.",
+		markdown:  "Just a short note.",
+	},
+	{
+		name:      "entities",
+		html:      "<p>Fish &amp; chips &lt;3 &#8220;yum&#8221;</p>",
+		plainText: "Fish & chips <3 “yum”",
+		markdown:  "Fish &amp; chips &lt;3 “yum”",
+	},
+	{
+		name:      "escaped markup",
+		html:      "<p>a &lt;b&gt;alert(1)&lt;/b&gt;</p>",
+		plainText: "a <b>alert(1)</b>",
+		markdown:  "a &lt;b&gt;alert(1)&lt;/b&gt;",
+	},
+	{
+		name:      "block syntax in text",
+		html:      "<p># not heading</p><p>1. not a list</p><p>- nor this<br>+ or this<br>2) or this</p><p>---</p><blockquote><p># quoted</p></blockquote><ul><li>- item</li></ul>",
+		plainText: "# not heading\n\n1. not a list\n\n- nor this\n+ or this\n2) or this\n\n---\n\n> # quoted\n\n- - item",
+		markdown:  "\\# not heading\n\n1\\. not a list\n\n\\- nor this  \n\\+ or this  \n2\\) or this\n\n\\---\n\n> \\# quoted\n\n- \\- item",
+	},
+	{
+		name:      "backticks in code",
+		html:      "<p><code>a`b</code> and <code>`x`</code></p><pre># a ``` fence\n1. item</pre>",
+		plainText: "a`b and `x`\n\n# a ``` fence\n1. item",
+		markdown:  "``a`b`` and `` `x` ``\n\n````\n# a ``` fence\n1. item\n````",
+	},
+	{
+		name:      "links",
+		html:      `<p>Read <a href="https://example.com/post">this post</a> on <a href="https://example.com">example.com</a>.</p>`,
+		plainText: "Read this post (https://example.com/post) on example.com.",
+		markdown:  "Read [this post](https://example.com/post) on [example.com](https://example.com).",
+	},
+	{
+		name:      "mentions",
+		html:      `<p><a href="https://micro.blog/jean_doe">@jean_doe</a> thanks!</p>`,
+		plainText: "@jean_doe thanks!",
+		markdown:  "@jean\\_doe thanks!",
+	},
+	{
+		name:      "images",
+		html:      `<p>Sunset:</p><p><img src="https://example.com/sun.jpg" alt="The sun going down"></p><img src="https://example.com/2.jpg">`,
+		plainText: "Sunset:\n\n[Image: The sun going down]\n\n[Image: https://example.com/2.jpg]",
+		markdown:  "Sunset:\n\n![The sun going down](https://example.com/sun.jpg)\n\n![](https://example.com/2.jpg)",
+	},
+	{
+		name:      "link destinations",
+		html:      `<p><a href="https://example.org/a)b">paren</a> <a href="https://example.org/a b">space</a> <a href="https://x.org/>evil<>"></a> <a href="/a?b=1&amp;c=2"></a></p><img src="https://example.org/a (1).jpg" alt="one">`,
+		plainText: "paren (https://example.org/a)b) space (https://example.org/a b) https://x.org/>evil<> /a?b=1&c=2\n\n[Image: one]",
+		markdown:  "[paren](https://example.org/a%29b) [space](https://example.org/a%20b) <https://x.org/%3Eevil%3C%3E> [/a?b=1&amp;c=2](/a?b=1&amp;c=2)\n\n![one](https://example.org/a%20%281%29.jpg)",
+	},
+	{
+		name:      "line breaks",
+		html:      "<p>One<br>Two<br/>Three</p>",
+		plainText: "One\nTwo\nThree",
+		markdown:  "One  \nTwo  \nThree",
+	},
+	{
+		name:      "blockquote",
+		html:      "<p>Quoting:</p><blockquote><p>First line.</p><p>Second line.</p></blockquote><p>Agreed.</p>",
+		plainText: "Quoting:\n\n> First line.\n>\n> Second line.\n\nAgreed.",
+		markdown:  "Quoting:\n\n> First line.\n>\n> Second line.\n\nAgreed.",
+	},
+	{
+		name:      "unordered list",
+		html:      "<ul><li>Apples</li><li>Pears<ul><li>Conference</li></ul></li></ul>",
+		plainText: "- Apples\n- Pears\n  - Conference",
+		markdown:  "- Apples\n- Pears\n  - Conference",
+	},
+	{
+		name:      "ordered list",
+		html:      "<ol><li>Open<li>Close</ol>",
+		plainText: "1. Open\n2. Close",
+		markdown:  "1. Open\n2. Close",
+	},
+	{
+		name:      "emphasis and code",
+		html:      "<p>This is <strong>important</strong>, <em>really</em>: <code>go test</code></p>",
+		plainText: "This is important, really: go test",
+		markdown:  "This is **important**, *really*: `go test`",
+	},
+	{
+		name:      "headings",
+		html:      "<h2>Title</h2><p>Body</p>",
+		plainText: "Title\n\nBody",
+		markdown:  "## Title\n\nBody",
+	},
+	{
+		name:      "preformatted",
+		html:      "<pre><code>func main() {\n    fmt.Println(\"hi\")\n}</code></pre>",
+		plainText: "func main() {\n    fmt.Println(\"hi\")\n}",
+		markdown:  "```\nfunc main() {\n    fmt.Println(\"hi\")\n}\n```",
+	},
+	{
+		name:      "markdown escaping",
+		html:      "<p>2 * 3 = 6 and snake_case</p>",
+		plainText: "2 * 3 = 6 and snake_case",
+		markdown:  `2 \* 3 = 6 and snake\_case`,
+	},
+	{
+		name:      "scripts and comments",
+		html:      "<p>Visible<!-- hidden --></p><script>if (a < b) { alert(1) }</script>",
+		plainText: "Visible",
+		markdown:  "Visible",
+	},
+	{
+		name:      "unclosed tags",
+		html:      "<p>One<p>Two <b>bold",
+		plainText: "One\n\nTwo bold",
+		markdown:  "One\n\nTwo **bold**",
+	},
+}
+
+func TestPlainText(t *testing.T) {
+	for _, test := range renderTests {
+		post := Post{ContentHTML: test.html}
+		if got := post.PlainText(); got != test.plainText {
+			t.Errorf("%s: PlainText() = %q, want %q", test.name, got, test.plainText)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	for _, test := range renderTests {
+		post := Post{ContentHTML: test.html}
+		if got := post.Markdown(); got != test.markdown {
+			t.Errorf("%s: Markdown() = %q, want %q", test.name, got, test.markdown)
+		}
+	}
+}
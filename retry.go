@@ -0,0 +1,194 @@
+package microdotblog
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried.
+// Requests are retried on network errors, on 429 Too Many Requests
+// and on 5xx responses. The zero value disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It doubles
+	// with every retry, and a random jitter is applied.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts.
+	MaxDelay time.Duration
+	// RetryNonIdempotent also retries requests that create content,
+	// like Post and Reply, which may then be created more than once.
+	RetryNonIdempotent bool
+	// OnAttempt is called after every attempt, e.g. for logging.
+	OnAttempt func(RetryAttempt)
+}
+
+// RetryAttempt describes a single attempt at sending a request.
+type RetryAttempt struct {
+	Method string
+	URL    string
+	// Attempt is 1 for the first attempt.
+	Attempt int
+	// StatusCode is 0 if no response was received.
+	StatusCode int
+	Err        error
+	// Retry is true if the request will be sent again after Delay.
+	Retry bool
+	Delay time.Duration
+}
+
+// DefaultRetryPolicy makes three attempts with a short backoff.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// backoff returns the delay before the next attempt. A Retry-After
+// header on the response takes precedence over the exponential backoff.
+func (p RetryPolicy) backoff(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if delay, ok := retryAfter(res.Header.Get("Retry-After")); ok {
+			if p.MaxDelay > 0 && delay > p.MaxDelay {
+				return p.MaxDelay
+			}
+			return delay
+		}
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	// Wait somewhere between half and all of the delay so that
+	// clients failing at the same time don't retry at the same time.
+	half := int64(delay / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// retryAfter parses a Retry-After header given either in seconds
+// or as an HTTP date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+func retryable(ctx context.Context, res *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if res == nil {
+		return err != nil
+	}
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+}
+
+// cancelBody cancels the context of a request when its body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// do sends r and checks the response for errors. If the request fails it
+// is sent again according to the retry policy, as long as it is idempotent
+// or the policy allows retrying non-idempotent requests.
+// The client's timeout applies to each attempt on its own.
+// On success the caller must close the body of the returned response.
+func (a aClient) do(ctx context.Context, r request) (*http.Request, *http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := a.newRequest(ctx, r)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		limiter := a.limiter(req.Method)
+		if err = limiter.Wait(ctx); err != nil {
+			return req, nil, newNetworkError(req, err)
+		}
+
+		// Every attempt gets the full timeout, which runs until the
+		// body is closed so that it also covers reading the response.
+		attemptCtx, cancel := a.withTimeout(ctx)
+		res, err := a.httpClient.Do(req.WithContext(attemptCtx))
+		if err != nil {
+			cancel()
+			res = nil
+			err = newNetworkError(req, err)
+		} else {
+			res.Body = cancelBody{res.Body, cancel}
+			limiter.observe(res)
+			if res.StatusCode != http.StatusNotModified || !r.conditional() {
+				err = newAPIError(req, res)
+			}
+		}
+
+		retry := err != nil &&
+			attempt < a.retry.MaxAttempts &&
+			(r.idempotent || a.retry.RetryNonIdempotent) &&
+			retryable(ctx, res, err)
+
+		var delay time.Duration
+		if retry {
+			delay = a.retry.backoff(attempt, res)
+		}
+
+		if a.retry.OnAttempt != nil {
+			info := RetryAttempt{
+				Method:  req.Method,
+				URL:     req.URL.String(),
+				Attempt: attempt,
+				Err:     err,
+				Retry:   retry,
+				Delay:   delay,
+			}
+			if res != nil {
+				info.StatusCode = res.StatusCode
+			}
+			a.retry.OnAttempt(info)
+		}
+
+		if err == nil {
+			return req, res, nil
+		}
+		if !retry {
+			return req, nil, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return req, nil, err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package microdotblog
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func makeRetryingClient(policy RetryPolicy, handler http.HandlerFunc) APIClient {
+	return apiClient{
+		httpClient: aClient{
+			httpClient: handlerClient{handler: handler},
+			token:      "ABCD12345",
+			retry:      policy,
+		},
+	}
+}
+
+func TestRetryServerError(t *testing.T) {
+	var attempts []RetryAttempt
+	policy := RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		OnAttempt: func(a RetryAttempt) {
+			attempts = append(attempts, a)
+		},
+	}
+
+	calls := 0
+	c := makeRetryingClient(policy, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(posts))
+	})
+
+	if _, err := c.GetPosts(); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+	if len(attempts) != 3 || !attempts[0].Retry || attempts[0].StatusCode != 502 || attempts[2].Retry || attempts[2].Err != nil {
+		t.Errorf("Unexpected attempts %+v", attempts)
+	}
+}
+
+func TestRetryTimeoutPerAttempt(t *testing.T) {
+	calls := 0
+	c := apiClient{
+		httpClient: aClient{
+			httpClient: handlerClient{handler: func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls == 1 {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				w.Write([]byte(posts))
+			}},
+			token: "ABCD12345",
+			retry: RetryPolicy{MaxAttempts: 2, BaseDelay: 60 * time.Millisecond},
+			// Shorter than the backoff, which must not count.
+			timeout: 20 * time.Millisecond,
+		},
+	}
+
+	if _, err := c.GetPosts(); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	calls := 0
+	c := makeRetryingClient(RetryPolicy{MaxAttempts: 2}, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	_, err := c.GetPosts()
+	if _, ok := err.(ServerError); !ok {
+		t.Errorf("Expected server error, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	calls := 0
+	c := makeRetryingClient(RetryPolicy{MaxAttempts: 3}, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	c.GetPosts()
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	calls := 0
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	c := makeRetryingClient(RetryPolicy{MaxAttempts: 3}, handler)
+	c.Post("Hello")
+	if calls != 1 {
+		t.Errorf("Expected posts not to be retried, got %d calls", calls)
+	}
+
+	calls = 0
+	c.Favourite(1234)
+	if calls != 3 {
+		t.Errorf("Expected favourites to be retried, got %d calls", calls)
+	}
+
+	calls = 0
+	c = makeRetryingClient(RetryPolicy{MaxAttempts: 3, RetryNonIdempotent: true}, handler)
+	c.Post("Hello")
+	if calls != 3 {
+		t.Errorf("Expected posts to be retried when opted in, got %d calls", calls)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Hour, MaxDelay: time.Minute}
+	res := &http.Response{Header: http.Header{"Retry-After": {"2"}}}
+	if delay := policy.backoff(1, res); delay != 2*time.Second {
+		t.Errorf("Expected delay from Retry-After, got %v", delay)
+	}
+
+	res.Header.Set("Retry-After", "3600")
+	if delay := policy.backoff(1, res); delay != time.Minute {
+		t.Errorf("Expected delay to be capped, got %v", delay)
+	}
+
+	policy = RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		delay := policy.backoff(attempt+1, nil)
+		if delay < max/2 || delay > max {
+			t.Errorf("Expected delay for attempt %d between %v and %v, got %v", attempt+1, max/2, max, delay)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package microdotblog
+
+import (
+	"sort"
+	"strings"
+)
+
+// Thread is a conversation arranged as a tree of replies.
+//
+// Build one from the feed returned by GetConversation:
+//
+//	feed, err := client.GetConversation(id)
+//	if err != nil {
+//		...
+//	}
+//	thread := microdotblog.NewThread(feed)
+//	thread.Walk(func(n *microdotblog.ThreadNode) bool {
+//		fmt.Printf("%s%s\n", strings.Repeat("  ", n.Depth), n.Post.PlainText())
+//		return true
+//	})
+type Thread struct {
+	// Root is the post that started the conversation.
+	// It is nil if the feed has no posts.
+	Root *ThreadNode
+	// Participants are the authors in the conversation
+	// in the order they joined it.
+	Participants []Author
+
+	nodes map[int64]*ThreadNode
+}
+
+// ThreadNode is a post in a thread and the replies to it.
+type ThreadNode struct {
+	Post   Post
+	Parent *ThreadNode
+	// Children are the replies to the post, oldest first.
+	Children []*ThreadNode
+	// Depth is 0 for the root, 1 for replies to the root and so on.
+	Depth int
+}
+
+// NewThread builds a thread from the posts in a conversation feed.
+//
+// A post is placed below the post it replies to when the feed says which
+// one that is. Otherwise it is placed below the latest earlier post by the
+// first author it mentions, and below the root if it mentions no one
+// in the thread. The oldest post is the root.
+func NewThread(feed *Feed) *Thread {
+	t := &Thread{nodes: map[int64]*ThreadNode{}}
+	if feed == nil || len(feed.Items) == 0 {
+		return t
+	}
+
+	posts := make([]Post, len(feed.Items))
+	copy(posts, feed.Items)
+	sort.SliceStable(posts, func(i, j int) bool {
+		a, b := posts[i], posts[j]
+		if !a.DatePublished.Equal(b.DatePublished) {
+			return a.DatePublished.Before(b.DatePublished)
+		}
+		return a.ID < b.ID
+	})
+
+	// Posts are added oldest first, so a parent is always added before
+	// its replies and the tree cannot have cycles.
+	var added []*ThreadNode
+	byURL := map[string]*ThreadNode{}
+	seen := map[string]bool{}
+	for _, p := range posts {
+		if _, ok := t.nodes[p.ID]; ok && p.ID != 0 {
+			continue
+		}
+
+		n := &ThreadNode{Post: p}
+		if t.Root == nil {
+			t.Root = n
+		} else {
+			parent := t.replyParent(p, byURL)
+			if parent == nil {
+				parent = mentionParent(p, added)
+			}
+			if parent == nil {
+				parent = t.Root
+			}
+			n.Parent = parent
+			n.Depth = parent.Depth + 1
+			parent.Children = append(parent.Children, n)
+		}
+
+		added = append(added, n)
+		t.nodes[p.ID] = n
+		if p.URL != "" {
+			byURL[p.URL] = n
+		}
+		if key := authorKey(p.Author); key != "" && !seen[key] {
+			seen[key] = true
+			t.Participants = append(t.Participants, p.Author)
+		}
+	}
+	return t
+}
+
+// replyParent finds the parent of p from its in-reply-to data.
+func (t *Thread) replyParent(p Post, byURL map[string]*ThreadNode) *ThreadNode {
+	if id := p.MicroblogProperties.InReplyToID; id != 0 {
+		if n, ok := t.nodes[id]; ok {
+			return n
+		}
+	}
+	if u := p.MicroblogProperties.InReplyToURL; u != "" {
+		return byURL[u]
+	}
+	return nil
+}
+
+// mentionParent guesses the parent of p from the users it mentions.
+func mentionParent(p Post, earlier []*ThreadNode) *ThreadNode {
+	for _, m := range p.Entities().Mentions {
+		for i := len(earlier) - 1; i >= 0; i-- {
+			if strings.EqualFold(earlier[i].Post.Author.MicroblogProperties.Username, m.Username) {
+				return earlier[i]
+			}
+		}
+	}
+	return nil
+}
+
+func authorKey(a Author) string {
+	if a.MicroblogProperties.Username != "" {
+		return strings.ToLower(a.MicroblogProperties.Username)
+	}
+	if a.URL != "" {
+		return a.URL
+	}
+	return a.Name
+}
+
+// Len returns the number of posts in the thread.
+func (t *Thread) Len() int {
+	return len(t.nodes)
+}
+
+// Find returns the node of the post with the given ID, or nil.
+func (t *Thread) Find(id int64) *ThreadNode {
+	return t.nodes[id]
+}
+
+// Walk calls fn for each post in the thread, depth first, starting with
+// the root and visiting replies oldest first. If fn returns false the
+// replies to that post are skipped.
+func (t *Thread) Walk(fn func(n *ThreadNode) bool) {
+	if t.Root != nil {
+		t.Root.walk(fn)
+	}
+}
+
+func (n *ThreadNode) walk(fn func(n *ThreadNode) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, child := range n.Children {
+		child.walk(fn)
+	}
+}
+
+// Posts returns the posts in the order Walk visits them.
+func (t *Thread) Posts() []Post {
+	var posts []Post
+	t.Walk(func(n *ThreadNode) bool {
+		posts = append(posts, n.Post)
+		return true
+	})
+	return posts
+}
@@ -0,0 +1,111 @@
+package microdotblog
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func threadPost(id int64, username, content string, minute int) Post {
+	p := Post{
+		ID:            id,
+		URL:           "https://example.com/" + username + "/" + string(rune('a'+id)),
+		ContentHTML:   content,
+		DatePublished: time.Date(2020, 1, 1, 12, minute, 0, 0, time.UTC),
+	}
+	p.Author.Name = username
+	p.Author.MicroblogProperties.Username = username
+	return p
+}
+
+func threadIDs(t *Thread) []int64 {
+	var ids []int64
+	for _, p := range t.Posts() {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func TestNewThreadWithReplyData(t *testing.T) {
+	root := threadPost(1, "manton", "<p>Hello</p>", 0)
+	a := threadPost(2, "jean", "<p>Hi</p>", 1)
+	a.MicroblogProperties.InReplyToID = 1
+	b := threadPost(3, "amy", "<p>Hey</p>", 2)
+	b.MicroblogProperties.InReplyToURL = root.URL
+	c := threadPost(4, "manton", "<p>Welcome</p>", 3)
+	c.MicroblogProperties.InReplyToID = 2
+
+	// Conversations come newest first.
+	thread := NewThread(&Feed{Items: []Post{c, b, a, root}})
+
+	if thread.Root == nil || thread.Root.Post.ID != 1 {
+		t.Fatalf("expected post 1 as root, got %+v", thread.Root)
+	}
+	if ids := threadIDs(thread); !reflect.DeepEqual(ids, []int64{1, 2, 4, 3}) {
+		t.Errorf("unexpected walk order %v", ids)
+	}
+	if n := thread.Find(4); n == nil || n.Depth != 2 || n.Parent.Post.ID != 2 {
+		t.Errorf("unexpected node for post 4: %+v", n)
+	}
+	if thread.Len() != 4 {
+		t.Errorf("expected 4 posts, got %d", thread.Len())
+	}
+
+	var participants []string
+	for _, a := range thread.Participants {
+		participants = append(participants, a.MicroblogProperties.Username)
+	}
+	if !reflect.DeepEqual(participants, []string{"manton", "jean", "amy"}) {
+		t.Errorf("unexpected participants %v", participants)
+	}
+}
+
+func TestNewThreadWithMentions(t *testing.T) {
+	feed := &Feed{Items: []Post{
+		threadPost(1, "manton", "<p>Hello</p>", 0),
+		threadPost(2, "jean", `<p><a href="https://micro.blog/manton">@manton</a> Hi</p>`, 1),
+		threadPost(3, "amy", `<p>@Jean hey</p>`, 2),
+		threadPost(4, "bob", `<p>Nice</p>`, 3),
+		threadPost(5, "manton", `<p>@someone @jean thanks</p>`, 4),
+	}}
+
+	thread := NewThread(feed)
+
+	parents := map[int64]int64{}
+	thread.Walk(func(n *ThreadNode) bool {
+		if n.Parent != nil {
+			parents[n.Post.ID] = n.Parent.Post.ID
+		}
+		return true
+	})
+	want := map[int64]int64{2: 1, 3: 2, 4: 1, 5: 2}
+	if !reflect.DeepEqual(parents, want) {
+		t.Errorf("expected parents %v, got %v", want, parents)
+	}
+}
+
+func TestThreadWalkSkip(t *testing.T) {
+	root := threadPost(1, "manton", "<p>Hello</p>", 0)
+	a := threadPost(2, "jean", "<p>Hi</p>", 1)
+	a.MicroblogProperties.InReplyToID = 1
+	b := threadPost(3, "amy", "<p>Hey</p>", 2)
+	b.MicroblogProperties.InReplyToID = 2
+
+	thread := NewThread(&Feed{Items: []Post{root, a, b}})
+
+	var visited []int64
+	thread.Walk(func(n *ThreadNode) bool {
+		visited = append(visited, n.Post.ID)
+		return n.Depth < 1
+	})
+	if !reflect.DeepEqual(visited, []int64{1, 2}) {
+		t.Errorf("unexpected visits %v", visited)
+	}
+}
+
+func TestNewThreadEmpty(t *testing.T) {
+	thread := NewThread(&Feed{})
+	if thread.Root != nil || thread.Len() != 0 || len(thread.Posts()) != 0 {
+		t.Errorf("expected an empty thread, got %+v", thread)
+	}
+}
@@ -0,0 +1,163 @@
+package microdotblog
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultWatchInterval is how long a Watcher waits between polls when
+// the server doesn't say how long to wait.
+const DefaultWatchInterval = time.Minute
+
+// Watcher polls the current user's timeline and sends new posts on a
+// channel. It uses Check to find out if there are new posts and how long
+// to wait before checking again, and only fetches the timeline when there
+// is something new.
+//
+// Save LastID when the watcher stops and pass it to NewWatcher to resume
+// where it left off.
+type Watcher struct {
+	// Interval is used when the server doesn't return check_seconds.
+	Interval time.Duration
+	// OnError is called when polling fails. The watcher keeps polling.
+	OnError func(error)
+
+	client APIClient
+	mu     sync.Mutex
+	lastID int64
+}
+
+// NewWatcher creates a watcher that sends the posts newer than the post
+// with ID sinceID. If sinceID is 0 it starts with the posts currently on
+// the first page of the timeline.
+func NewWatcher(client APIClient, sinceID int64) *Watcher {
+	return &Watcher{
+		Interval: DefaultWatchInterval,
+		client:   client,
+		lastID:   sinceID,
+	}
+}
+
+// LastID returns the ID of the newest post sent so far.
+func (w *Watcher) LastID() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lastID
+}
+
+// Watch starts polling. New posts are sent on the returned channel, oldest
+// first and each post only once. The channel is closed when ctx is done.
+func (w *Watcher) Watch(ctx context.Context) <-chan Post {
+	posts := make(chan Post)
+	go w.run(ctx, posts)
+	return posts
+}
+
+func (w *Watcher) run(ctx context.Context, posts chan<- Post) {
+	defer close(posts)
+
+	for {
+		wait := w.Interval
+		if wait <= 0 {
+			wait = DefaultWatchInterval
+		}
+
+		if w.LastID() == 0 {
+			// Without a last ID, Check and fetch would walk the whole
+			// timeline, so keep to the first page until there is one.
+			feed, err := w.client.GetPostsContext(ctx)
+			if err != nil {
+				w.error(ctx, err)
+			} else if !w.send(ctx, posts, feed.Items) {
+				return
+			}
+		} else {
+			check, err := w.client.CheckContext(ctx, w.LastID())
+			if err != nil {
+				w.error(ctx, err)
+			} else {
+				if check.CheckSeconds > 0 {
+					wait = time.Duration(check.CheckSeconds) * time.Second
+				}
+				if check.Count > 0 {
+					// On errors nothing is sent and the last ID stays put, so
+					// the next poll fetches the whole range again instead of
+					// skipping the pages that failed.
+					newPosts, err := w.fetch(ctx)
+					if err != nil {
+						w.error(ctx, err)
+					} else if !w.send(ctx, posts, newPosts) {
+						return
+					}
+				}
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// fetch gets all posts newer than the last ID, following pages back
+// in time if there are more than fit on a single page.
+func (w *Watcher) fetch(ctx context.Context) ([]Post, error) {
+	var posts []Post
+	it := NewFeedIterator(ctx, w.client.GetPostsContext, FeedOptions{SinceID: w.LastID()})
+	for it.Next() {
+		posts = append(posts, it.Feed().Items...)
+	}
+	return posts, it.Err()
+}
+
+// send sends the posts that are newer than the last ID, oldest first.
+// It returns false if ctx was done before all posts were sent.
+func (w *Watcher) send(ctx context.Context, posts chan<- Post, items []Post) bool {
+	lastID := w.LastID()
+
+	seen := map[int64]bool{}
+	var fresh []Post
+	for _, post := range items {
+		if post.ID <= lastID || seen[post.ID] {
+			continue
+		}
+		seen[post.ID] = true
+		fresh = append(fresh, post)
+	}
+
+	sort.Slice(fresh, func(i, j int) bool {
+		return fresh[i].ID < fresh[j].ID
+	})
+
+	for _, post := range fresh {
+		// Move the last ID before sending so that a receiver
+		// calling LastID sees the post it just received.
+		w.setLastID(post.ID)
+		select {
+		case <-ctx.Done():
+			w.setLastID(lastID)
+			return false
+		case posts <- post:
+			lastID = post.ID
+		}
+	}
+	return true
+}
+
+func (w *Watcher) setLastID(ID int64) {
+	w.mu.Lock()
+	w.lastID = ID
+	w.mu.Unlock()
+}
+
+func (w *Watcher) error(ctx context.Context, err error) {
+	if w.OnError != nil && ctx.Err() == nil {
+		w.OnError(err)
+	}
+}
@@ -0,0 +1,202 @@
+package microdotblog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	var mu sync.Mutex
+	ids := []int64{3, 2, 1}
+
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		sinceID, _ := strconv.ParseInt(r.URL.Query().Get("since_id"), 10, 64)
+		beforeID, _ := strconv.ParseInt(r.URL.Query().Get("before_id"), 10, 64)
+
+		var items []string
+		for _, id := range ids {
+			if id > sinceID && (beforeID == 0 || id < beforeID) {
+				items = append(items, fmt.Sprintf(`{"id":"%d"}`, id))
+			}
+		}
+
+		if r.URL.Path == "/posts/check" {
+			fmt.Fprintf(w, `{"count":%d}`, len(items))
+			return
+		}
+		// Return the same post twice to check that it is only sent once.
+		if len(items) > 0 {
+			items = append(items, items[0])
+		}
+		fmt.Fprintf(w, `{"items":[%s]}`, strings.Join(items, ","))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watcher := NewWatcher(c, 1)
+	watcher.Interval = 5 * time.Millisecond
+	watcher.OnError = func(err error) {
+		t.Error(err)
+	}
+	posts := watcher.Watch(ctx)
+
+	expectPost := func(expected int64) {
+		select {
+		case post := <-posts:
+			if post.ID != expected {
+				t.Errorf("Expected post %d, got %d", expected, post.ID)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for post %d", expected)
+		}
+	}
+
+	expectPost(2)
+	expectPost(3)
+
+	mu.Lock()
+	ids = append([]int64{5, 4}, ids...)
+	mu.Unlock()
+
+	expectPost(4)
+	expectPost(5)
+
+	if lastID := watcher.LastID(); lastID != 5 {
+		t.Errorf("Expected last ID 5, got %d", lastID)
+	}
+
+	cancel()
+	for range posts {
+		t.Error("Expected no more posts after cancelling")
+	}
+}
+
+func TestWatcherRefetchesAfterFailedPage(t *testing.T) {
+	var mu sync.Mutex
+	failOlderPage := true
+
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		sinceID, _ := strconv.ParseInt(r.URL.Query().Get("since_id"), 10, 64)
+		beforeID, _ := strconv.ParseInt(r.URL.Query().Get("before_id"), 10, 64)
+
+		if r.URL.Path == "/posts/check" {
+			fmt.Fprintf(w, `{"count":%d}`, 5-sinceID)
+			return
+		}
+		if beforeID != 0 && failOlderPage {
+			failOlderPage = false
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// Pages hold two posts each, newest first.
+		var items []string
+		for id := int64(5); id > sinceID && len(items) < 2; id-- {
+			if beforeID == 0 || id < beforeID {
+				items = append(items, fmt.Sprintf(`{"id":"%d"}`, id))
+			}
+		}
+		fmt.Fprintf(w, `{"items":[%s]}`, strings.Join(items, ","))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 10)
+	watcher := NewWatcher(c, 1)
+	watcher.Interval = 5 * time.Millisecond
+	watcher.OnError = func(err error) {
+		errs <- err
+	}
+	posts := watcher.Watch(ctx)
+
+	for _, expected := range []int64{2, 3, 4, 5} {
+		select {
+		case post := <-posts:
+			if post.ID != expected {
+				t.Fatalf("Expected post %d, got %d", expected, post.ID)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for post %d", expected)
+		}
+	}
+
+	select {
+	case <-errs:
+	default:
+		t.Error("Expected the failed page to be reported")
+	}
+	if lastID := watcher.LastID(); lastID != 5 {
+		t.Errorf("Expected last ID 5, got %d", lastID)
+	}
+}
+
+func TestWatcherRetriesFirstPage(t *testing.T) {
+	var mu sync.Mutex
+	failFirstPage := true
+
+	c := makeHandlerClient("ABCD12345", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		sinceID, _ := strconv.ParseInt(r.URL.Query().Get("since_id"), 10, 64)
+		beforeID, _ := strconv.ParseInt(r.URL.Query().Get("before_id"), 10, 64)
+
+		if r.URL.Path == "/posts/check" {
+			fmt.Fprintf(w, `{"count":%d}`, 30-sinceID)
+			return
+		}
+		if failFirstPage {
+			failFirstPage = false
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// Pages hold three of the 30 posts each, newest first.
+		var items []string
+		for id := int64(30); id > sinceID && len(items) < 3; id-- {
+			if beforeID == 0 || id < beforeID {
+				items = append(items, fmt.Sprintf(`{"id":"%d"}`, id))
+			}
+		}
+		fmt.Fprintf(w, `{"items":[%s]}`, strings.Join(items, ","))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watcher := NewWatcher(c, 0)
+	watcher.Interval = 5 * time.Millisecond
+	watcher.OnError = func(err error) {}
+	posts := watcher.Watch(ctx)
+
+	for _, expected := range []int64{28, 29, 30} {
+		select {
+		case post := <-posts:
+			if post.ID != expected {
+				t.Fatalf("Expected post %d, got %d", expected, post.ID)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for post %d", expected)
+		}
+	}
+
+	select {
+	case post := <-posts:
+		t.Errorf("Expected only the first page, got post %d", post.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}